@@ -1,11 +1,155 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/unitchecker"
 
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/driver"
 	"github.com/m-v-kalashnikov/perfcheck/go/pkg/perfchecklint"
 )
 
+// main serves three modes: the go vet -vettool protocol (unitchecker), the
+// `sarif` converter for vet JSON, and a standalone driver that loads the
+// package patterns itself, e.g. `perfcheck-go -fix ./...`.
 func main() {
-	unitchecker.Main(perfchecklint.Analyzers()...)
+	args := os.Args[1:]
+	switch {
+	case len(args) == 1 && args[0] == "-V=full":
+		printVersion()
+	case len(args) > 0 && args[0] == "sarif":
+		os.Exit(runSARIF(args[1:]))
+	case len(args) > 0 && strings.HasSuffix(args[len(args)-1], ".cfg"):
+		unit := args[len(args)-1]
+		if vetxOnly(unit) {
+			unitchecker.Main(factAnalyzers()...)
+		}
+		checkConfig(unit)
+		unitchecker.Main(perfchecklint.Analyzers()...)
+	case isVetProtocol(args):
+		unitchecker.Main(perfchecklint.Analyzers()...)
+	default:
+		os.Exit(driver.Main(args, os.Stdout, os.Stderr))
+	}
+}
+
+// isVetProtocol reports the invocations the go command and humans make of a
+// vettool that must keep their unitchecker behavior.
+func isVetProtocol(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "help", "-flags", "-V", "-V=full":
+		return true
+	}
+	return false
+}
+
+// printVersion mirrors the unitchecker -V=full output but folds the active
+// .perfcheck.yaml and pprof profile into the build ID, so the go command stops
+// replaying cached vet results once either changes.
+func printVersion() {
+	progname, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	f, err := os.Open(progname)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		log.Fatal(err)
+	}
+	f.Close()
+
+	config := activeConfig()
+	for _, input := range []string{config, activeProfile(config)} {
+		if input == "" {
+			continue
+		}
+		if data, err := os.ReadFile(input); err == nil {
+			h.Write(data)
+		}
+	}
+	fmt.Printf("%s version devel comments-go-here buildID=%02x\n", progname, h.Sum(nil))
+}
+
+// checkConfig fails the unit up front when its configuration or profile is
+// invalid; the go/analysis driver would otherwise only report a failed
+// prerequisite.
+func checkConfig(unitConfig string) {
+	var cfg *perfchecklint.Config
+	if config := activeConfig(); config != "" {
+		var err error
+		if cfg, err = perfchecklint.LoadConfig(config); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if profile := cfg.ProfilePath(); profile != "" {
+		var sampleType string
+		if cfg != nil {
+			sampleType = cfg.Profile.SampleType
+		}
+		if _, err := perfchecklint.LoadProfile(profile, sampleType); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// factAnalyzers is the analyzer set for dependencies that go vet analyzes
+// only for facts. unitchecker would otherwise run every analyzer that
+// requires a fact producer, which here means the whole rule set, on each
+// dependency. Rule analyzers are replaced by flag-only stubs so the flags go
+// vet passes to every unit still parse.
+func factAnalyzers() []*analysis.Analyzer {
+	analyzers := perfchecklint.FactAnalyzers()
+	for _, a := range perfchecklint.Analyzers() {
+		analyzers = append(analyzers, &analysis.Analyzer{
+			Name:  a.Name,
+			Doc:   a.Doc,
+			Flags: a.Flags,
+			Run:   func(*analysis.Pass) (any, error) { return nil, nil },
+		})
+	}
+	return analyzers
+}
+
+// vetxOnly reports whether the unit configuration written by the go command
+// asks for facts only.
+func vetxOnly(unitConfig string) bool {
+	data, err := os.ReadFile(unitConfig)
+	if err != nil {
+		return false
+	}
+	var unit struct{ VetxOnly bool }
+	return json.Unmarshal(data, &unit) == nil && unit.VetxOnly
+}
+
+// activeConfig returns the configuration that applies to the working
+// directory, which the go command sets to the package directory for vet.
+func activeConfig() string {
+	if config := os.Getenv(perfchecklint.ConfigEnv); config != "" {
+		return config
+	}
+	config, _ := perfchecklint.FindConfig(".")
+	return config
+}
+
+// activeProfile returns the pprof profile named by PERFCHECK_PROFILE or by
+// the configuration file, if any.
+func activeProfile(config string) string {
+	var cfg *perfchecklint.Config
+	if config != "" {
+		cfg, _ = perfchecklint.LoadConfig(config)
+	}
+	return cfg.ProfilePath()
 }
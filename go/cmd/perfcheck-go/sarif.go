@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/sarif"
+)
+
+// runSARIF converts `go vet -vettool=perfcheck-go -json` output into a SARIF
+// 2.1.0 log:
+//
+//	go vet -vettool=$(which perfcheck-go) -json ./... 2>&1 | perfcheck-go sarif -o perfcheck.sarif
+func runSARIF(args []string) int {
+	fs := flag.NewFlagSet("perfcheck-go sarif", flag.ContinueOnError)
+	output := fs.String("o", "", "write the SARIF log to `file` instead of stdout")
+	base := fs.String("base", ".", "report file locations relative to `dir` (%SRCROOT%)")
+	config := fs.String("config", "", "take result levels from this .perfcheck.yaml `file` (default: discovered per file)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: perfcheck-go sarif [-o file] [-base dir] [-config file] [vet-json-file]\n\n"+
+			"Reads `go vet -json` output from the file or stdin and writes SARIF 2.1.0.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "perfcheck-go sarif:", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	findings, notifications, err := sarif.ParseVetJSON(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "perfcheck-go sarif:", err)
+		return 1
+	}
+	if err := writeSARIF(*output, findings, sarif.Options{
+		BaseDir:       *base,
+		Version:       toolVersion(),
+		ConfigFile:    *config,
+		Notifications: notifications,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "perfcheck-go sarif:", err)
+		return 1
+	}
+	return 0
+}
+
+func writeSARIF(output string, findings []sarif.Finding, opts sarif.Options) error {
+	log, err := sarif.New(findings, opts)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		return log.Write(os.Stdout)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := log.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+	return info.Main.Version
+}
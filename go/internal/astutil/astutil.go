@@ -0,0 +1,51 @@
+// Package astutil holds syntax helpers shared by the analyzers and the
+// standalone driver.
+package astutil
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// EnclosingFuncDecl returns the function declaration in files that contains
+// pos, or nil for positions outside any function body or signature.
+func EnclosingFuncDecl(files []*ast.File, pos token.Pos) *ast.FuncDecl {
+	for _, file := range files {
+		if pos < file.FileStart || pos > file.FileEnd {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Pos() <= pos && pos < fn.End() {
+				return fn
+			}
+		}
+	}
+	return nil
+}
+
+// FuncName names decl the way the runtime does, without the package path:
+// "F", "T.M", or "(*T).M". Type parameters are omitted.
+func FuncName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	typ := decl.Recv.List[0].Type
+	pointer := false
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ, pointer = star.X, true
+	}
+	switch generic := typ.(type) {
+	case *ast.IndexExpr:
+		typ = generic.X
+	case *ast.IndexListExpr:
+		typ = generic.X
+	}
+	recv := "?"
+	if ident, ok := typ.(*ast.Ident); ok {
+		recv = ident.Name
+	}
+	if pointer {
+		return "(*" + recv + ")." + decl.Name.Name
+	}
+	return recv + "." + decl.Name.Name
+}
@@ -0,0 +1,159 @@
+// Package baseline records the perfcheck findings a project already has so
+// CI can fail only on new ones.
+//
+// Findings are keyed by rule ID, package path, enclosing function, and a
+// whitespace-normalized snippet of the reported source rather than by line
+// number, so unrelated edits that shift code around do not invalidate the
+// baseline. Identical keys are counted.
+package baseline
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Version is the baseline file format version written by Write.
+const Version = 1
+
+// Key identifies a finding independently of its line number.
+type Key struct {
+	Rule     string `json:"rule"`
+	Package  string `json:"package"`
+	Function string `json:"function,omitempty"`
+	Snippet  string `json:"snippet"`
+}
+
+// Entry is a Key along with how many findings share it.
+type Entry struct {
+	Key
+	Count int `json:"count"`
+}
+
+type file struct {
+	Version  int     `json:"version"`
+	Findings []Entry `json:"findings"`
+}
+
+// Baseline is a multiset of finding keys.
+type Baseline struct {
+	counts map[Key]int
+}
+
+// New builds a baseline holding keys.
+func New(keys []Key) *Baseline {
+	b := &Baseline{counts: make(map[Key]int, len(keys))}
+	for _, key := range keys {
+		b.counts[key]++
+	}
+	return b
+}
+
+// Load reads a baseline file written by Write.
+func Load(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("baseline: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", filename, err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("baseline %s: unsupported version %d (want %d)", filename, f.Version, Version)
+	}
+	b := &Baseline{counts: make(map[Key]int, len(f.Findings))}
+	for _, entry := range f.Findings {
+		if entry.Count <= 0 {
+			return nil, fmt.Errorf("baseline %s: %s entry has non-positive count %d", filename, entry.Rule, entry.Count)
+		}
+		b.counts[entry.Key] += entry.Count
+	}
+	return b, nil
+}
+
+// Write stores the baseline as JSON sorted by key, so regenerating it yields
+// reviewable diffs.
+func (b *Baseline) Write(filename string) error {
+	f := file{Version: Version, Findings: b.Entries()}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("baseline: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the baseline contents sorted by package, function, rule,
+// and snippet.
+func (b *Baseline) Entries() []Entry {
+	entries := make([]Entry, 0, len(b.counts))
+	for key, count := range b.counts {
+		entries = append(entries, Entry{Key: key, Count: count})
+	}
+	slices.SortFunc(entries, func(x, y Entry) int {
+		return cmp.Or(
+			cmp.Compare(x.Package, y.Package),
+			cmp.Compare(x.Function, y.Function),
+			cmp.Compare(x.Rule, y.Rule),
+			cmp.Compare(x.Snippet, y.Snippet),
+		)
+	})
+	return entries
+}
+
+// Len returns the number of findings in the baseline.
+func (b *Baseline) Len() int {
+	total := 0
+	for _, count := range b.counts {
+		total += count
+	}
+	return total
+}
+
+// Filter reports which of keys are new: a key is known while the baseline
+// still has an unclaimed finding with the same key, so a second copy of a
+// baselined finding counts as new.
+func (b *Baseline) Filter(keys []Key) []bool {
+	remaining := make(map[Key]int, len(b.counts))
+	for key, count := range b.counts {
+		remaining[key] = count
+	}
+	fresh := make([]bool, len(keys))
+	for i, key := range keys {
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		fresh[i] = true
+	}
+	return fresh
+}
+
+// Ratchet returns the baseline lowered to the counts still present in keys
+// and whether any count went down. New findings are never added.
+func (b *Baseline) Ratchet(keys []Key) (*Baseline, bool) {
+	current := New(keys)
+	next := &Baseline{counts: make(map[Key]int, len(b.counts))}
+	lowered := false
+	for key, count := range b.counts {
+		if have := current.counts[key]; have < count {
+			count, lowered = have, true
+		}
+		if count > 0 {
+			next.counts[key] = count
+		}
+	}
+	return next, lowered
+}
+
+// NormalizeSnippet collapses runs of whitespace so indentation and
+// formatting changes do not alter a key.
+func NormalizeSnippet(src string) string {
+	return strings.Join(strings.Fields(src), " ")
+}
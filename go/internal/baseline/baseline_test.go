@@ -0,0 +1,57 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	deferKey = Key{Rule: "perf_no_defer_in_loop", Package: "example.com/a", Function: "(*T).Close", Snippet: "defer f.Close()"}
+	foldKey  = Key{Rule: "perf_equal_fold_compare", Package: "example.com/a", Function: "Same", Snippet: "return a == b"}
+)
+
+func TestWriteLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "perfcheck-baseline.json")
+	require.NoError(t, New([]Key{foldKey, deferKey, deferKey}).Write(path))
+
+	loaded, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, 3, loaded.Len())
+	require.Equal(t, []Entry{{Key: deferKey, Count: 2}, {Key: foldKey, Count: 1}}, loaded.Entries())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 9, "findings": []}`), 0o644))
+	_, err = Load(path)
+	require.ErrorContains(t, err, "unsupported version 9")
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestFilterCountsDuplicates(t *testing.T) {
+	known := New([]Key{deferKey})
+	other := deferKey
+	other.Snippet = "defer g.Close()"
+
+	require.Equal(t, []bool{false, true, true}, known.Filter([]Key{deferKey, deferKey, other}))
+	require.Equal(t, []bool{false}, known.Filter([]Key{deferKey}), "Filter does not consume the baseline")
+}
+
+func TestRatchetOnlyLowers(t *testing.T) {
+	known := New([]Key{deferKey, deferKey, foldKey})
+
+	same, lowered := known.Ratchet([]Key{deferKey, deferKey, foldKey, {Rule: "perf_new"}})
+	require.False(t, lowered)
+	require.Equal(t, known.Entries(), same.Entries(), "new findings are not added")
+
+	next, lowered := known.Ratchet([]Key{deferKey})
+	require.True(t, lowered)
+	require.Equal(t, []Entry{{Key: deferKey, Count: 1}}, next.Entries())
+}
+
+func TestNormalizeSnippet(t *testing.T) {
+	require.Equal(t, "for _, f := range files { defer f.Close() }",
+		NormalizeSnippet("\tfor _, f := range files {\n\t\tdefer f.Close()\n\t}\n"))
+}
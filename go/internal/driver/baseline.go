@@ -0,0 +1,110 @@
+package driver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/astutil"
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/baseline"
+)
+
+// applyBaseline returns the findings missing from the baseline file. With
+// opts.WriteBaseline it records every finding instead and returns none; with
+// opts.Ratchet it lowers the baseline to the known findings still present.
+// Incomplete runs never rewrite the baseline, since findings in packages
+// that failed to load would otherwise be forgotten.
+func applyBaseline(findings []finding, opts Options, complete bool) ([]finding, error) {
+	path := opts.Baseline
+	if opts.Dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(opts.Dir, path)
+	}
+
+	keys := baselineKeys(findings)
+	if opts.WriteBaseline {
+		if !complete {
+			return nil, fmt.Errorf("not writing baseline %s: analysis was incomplete", opts.Baseline)
+		}
+		if err := baseline.New(keys).Write(path); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(opts.Stderr, "perfcheck-go: recorded %d findings in %s\n", len(keys), opts.Baseline)
+		return nil, nil
+	}
+
+	known, err := baseline.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	var fresh []finding
+	for i, isNew := range known.Filter(keys) {
+		if isNew {
+			fresh = append(fresh, findings[i])
+		}
+	}
+
+	if opts.Ratchet && complete {
+		if next, lowered := known.Ratchet(keys); lowered {
+			if err := next.Write(path); err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(opts.Stderr, "perfcheck-go: lowered baseline %s from %d to %d findings\n",
+				opts.Baseline, known.Len(), next.Len())
+		}
+	}
+	return fresh, nil
+}
+
+func baselineKeys(findings []finding) []baseline.Key {
+	sources := make(map[string][]byte)
+	keys := make([]baseline.Key, 0, len(findings))
+	for _, f := range findings {
+		key := baseline.Key{
+			Rule:    messageRuleID(f.diag.Message),
+			Package: f.pkgPath,
+			Snippet: snippet(f, sources),
+		}
+		if decl := astutil.EnclosingFuncDecl(f.files, f.diag.Pos); decl != nil {
+			key.Function = astutil.FuncName(decl)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// snippet returns the normalized source lines covered by the diagnostic.
+func snippet(f finding, sources map[string][]byte) string {
+	file := f.fset.File(f.diag.Pos)
+	if file == nil {
+		return ""
+	}
+	src, ok := sources[file.Name()]
+	if !ok {
+		src, _ = os.ReadFile(file.Name())
+		sources[file.Name()] = src
+	}
+
+	first, last := file.Line(f.diag.Pos), file.Line(f.diag.Pos)
+	if f.diag.End.IsValid() && f.diag.End > f.diag.Pos {
+		last = file.Line(f.diag.End)
+	}
+	start, end := file.Offset(file.LineStart(first)), len(src)
+	if last < file.LineCount() {
+		end = file.Offset(file.LineStart(last + 1))
+	}
+	if start > end || end > len(src) {
+		return ""
+	}
+	return baseline.NormalizeSnippet(string(src[start:end]))
+}
+
+func messageRuleID(message string) string {
+	if !strings.HasPrefix(message, "[") {
+		return ""
+	}
+	if end := strings.IndexByte(message, ']'); end > 1 {
+		return message[1:end]
+	}
+	return ""
+}
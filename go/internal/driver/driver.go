@@ -0,0 +1,375 @@
+// Package driver runs the perfcheck analyzers over packages it loads itself
+// with go/packages, so perfcheck-go works without the go vet plumbing.
+package driver
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/sarif"
+	"github.com/m-v-kalashnikov/perfcheck/go/pkg/perfchecklint"
+)
+
+// Exit statuses returned by Main, independent of the output format.
+const (
+	ExitOK       = 0 // no diagnostics remain
+	ExitError    = 1 // packages failed to load or an analyzer failed
+	ExitUsage    = 2 // invalid command line
+	ExitFindings = 3 // diagnostics were reported and not fixed
+)
+
+// Options carries the parsed command line.
+type Options struct {
+	JSON       bool
+	SARIF      bool
+	Fix        bool
+	Diff       bool
+	Tests      bool
+	Tags       string
+	Config     string
+	Profile    string
+	Sequential bool
+	Dir        string
+	Stdout     io.Writer
+	Stderr     io.Writer
+
+	// Baseline names a file of known findings that are not reported.
+	// WriteBaseline records the current findings there instead, and Ratchet
+	// lowers its counts whenever known findings disappear.
+	Baseline      string
+	WriteBaseline bool
+	Ratchet       bool
+}
+
+// finding is one deduplicated diagnostic along with the file set that owns
+// its positions.
+type finding struct {
+	analyzer string
+	pkg      string
+	pkgPath  string
+	files    []*ast.File
+	fset     *token.FileSet
+	diag     analysis.Diagnostic
+	posn     token.Position
+}
+
+// Main parses args (without the program name) and runs the analyzers over
+// the named package patterns, returning the process exit status.
+func Main(args []string, stdout, stderr io.Writer) int {
+	opts := Options{Stdout: stdout, Stderr: stderr}
+	fs := flag.NewFlagSet("perfcheck-go", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&opts.JSON, "json", false, "emit diagnostics as JSON in the go vet -json layout")
+	fs.BoolVar(&opts.SARIF, "sarif", false, "emit diagnostics as a SARIF 2.1.0 log")
+	fs.BoolVar(&opts.Fix, "fix", false, "apply suggested fixes to the source files")
+	fs.BoolVar(&opts.Diff, "diff", false, "print suggested fixes as unified diffs instead of applying them")
+	fs.BoolVar(&opts.Tests, "test", true, "also analyze test files")
+	fs.StringVar(&opts.Tags, "tags", "", "comma-separated build tags")
+	fs.StringVar(&opts.Config, "config", "", "path to a .perfcheck.yaml file (default: discovered per package)")
+	fs.StringVar(&opts.Profile, "profile", "", "pprof CPU or allocation profile used to weigh diagnostics (e.g. default.pgo)")
+	fs.BoolVar(&opts.Sequential, "sequential", false, "analyze packages one at a time")
+	fs.StringVar(&opts.Baseline, "baseline", "", "report only findings missing from this baseline file")
+	fs.BoolVar(&opts.WriteBaseline, "write-baseline", false, "record the current findings in the -baseline file")
+	fs.BoolVar(&opts.Ratchet, "ratchet", false, "rewrite the -baseline file when known findings have been fixed")
+	for _, analyzer := range perfchecklint.Analyzers() {
+		analyzer.Flags.VisitAll(func(f *flag.Flag) {
+			fs.Var(f.Value, analyzer.Name+"."+f.Name, f.Usage)
+		})
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: perfcheck-go [flags] packages...\n\n"+
+			"Runs the perfcheck analyzers over the packages. Exit status is 3 when\n"+
+			"diagnostics remain, 1 on load or analysis errors, and 0 otherwise.\n"+
+			"With -baseline, only findings missing from the baseline count.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() == 0 || (opts.JSON && opts.SARIF) ||
+		((opts.WriteBaseline || opts.Ratchet) && opts.Baseline == "") {
+		fs.Usage()
+		return ExitUsage
+	}
+	return Run(fs.Args(), opts)
+}
+
+// Run analyzes the packages matching patterns.
+func Run(patterns []string, opts Options) int {
+	bundle := perfchecklint.Build(perfchecklint.BuildOptions{ConfigFile: opts.Config, ProfileFile: opts.Profile})
+
+	cfg := &packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Dir:   opts.Dir,
+		Tests: opts.Tests,
+	}
+	if opts.Tags != "" {
+		cfg.BuildFlags = []string{"-tags=" + opts.Tags}
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		fmt.Fprintln(opts.Stderr, "perfcheck-go:", err)
+		return ExitError
+	}
+
+	var problems []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			problems = append(problems, err.Error())
+		}
+	})
+
+	graph, err := checker.Analyze(bundle.Analyzers, pkgs, &checker.Options{Sequential: opts.Sequential})
+	if err != nil {
+		fmt.Fprintln(opts.Stderr, "perfcheck-go:", err)
+		return ExitError
+	}
+
+	findings, failures := collect(graph)
+	if len(problems) == 0 {
+		// Load errors already explain why analyzers were skipped.
+		problems = append(problems, failures...)
+	}
+
+	if opts.Baseline != "" {
+		if findings, err = applyBaseline(findings, opts, len(problems) == 0); err != nil {
+			fmt.Fprintln(opts.Stderr, "perfcheck-go:", err)
+			return ExitError
+		}
+	}
+
+	if opts.Fix || opts.Diff {
+		if findings, err = applyFixes(findings, opts); err != nil {
+			fmt.Fprintln(opts.Stderr, "perfcheck-go:", err)
+			return ExitError
+		}
+	}
+
+	switch {
+	case opts.JSON:
+		if err := printJSON(opts.Stdout, findings, graph); err != nil {
+			fmt.Fprintln(opts.Stderr, "perfcheck-go:", err)
+			return ExitError
+		}
+	case opts.SARIF:
+		if err := printSARIF(opts, findings, problems); err != nil {
+			fmt.Fprintln(opts.Stderr, "perfcheck-go:", err)
+			return ExitError
+		}
+	default:
+		printText(opts, findings)
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(opts.Stderr, problem)
+	}
+	switch {
+	case len(problems) > 0:
+		return ExitError
+	case len(findings) > 0:
+		return ExitFindings
+	default:
+		return ExitOK
+	}
+}
+
+// collect flattens root diagnostics, dropping the duplicates reported for a
+// file that belongs to several package variants (such as its test variant).
+func collect(graph *checker.Graph) ([]finding, []string) {
+	var findings []finding
+	var failures []string
+	seen := make(map[string]bool)
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			cause := rootCause(act)
+			failure := fmt.Sprintf("%s: %s: %v", act.Package.ID, cause.Analyzer.Name, cause.Err)
+			if !seen[failure] {
+				seen[failure] = true
+				failures = append(failures, failure)
+			}
+			continue
+		}
+		for _, diag := range act.Diagnostics {
+			posn := act.Package.Fset.Position(diag.Pos)
+			key := posn.String() + "\x00" + diag.Message
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			findings = append(findings, finding{
+				analyzer: act.Analyzer.Name,
+				pkg:      act.Package.ID,
+				pkgPath:  act.Package.PkgPath,
+				files:    act.Package.Syntax,
+				fset:     act.Package.Fset,
+				diag:     diag,
+				posn:     posn,
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i].posn, findings[j].posn
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return findings[i].diag.Message < findings[j].diag.Message
+	})
+	return findings, failures
+}
+
+// rootCause follows failed prerequisites down to the action whose own Run
+// failed, so a broken configuration is reported once with its real error.
+func rootCause(act *checker.Action) *checker.Action {
+	for _, dep := range act.Deps {
+		if dep.Err != nil && dep.Package == act.Package {
+			return rootCause(dep)
+		}
+	}
+	return act
+}
+
+func printText(opts Options, findings []finding) {
+	for _, f := range findings {
+		posn := f.posn
+		posn.Filename = relativePath(opts.Dir, posn.Filename)
+		fmt.Fprintf(opts.Stderr, "%s: %s\n", posn, f.diag.Message)
+	}
+}
+
+// jsonDiagnostic matches the go vet -json schema so the output can be piped
+// into `perfcheck-go sarif` or existing vet tooling.
+type jsonDiagnostic struct {
+	Category       string             `json:"category,omitempty"`
+	Posn           string             `json:"posn"`
+	Message        string             `json:"message"`
+	SuggestedFixes []jsonSuggestedFix `json:"suggested_fixes,omitempty"`
+}
+
+type jsonSuggestedFix struct {
+	Message string         `json:"message"`
+	Edits   []jsonTextEdit `json:"edits"`
+}
+
+type jsonTextEdit struct {
+	Filename string `json:"filename"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	New      string `json:"new"`
+}
+
+func printJSON(w io.Writer, findings []finding, graph *checker.Graph) error {
+	tree := make(map[string]map[string]any)
+	for _, act := range graph.Roots {
+		if act.Err == nil {
+			continue
+		}
+		if tree[act.Package.ID] == nil {
+			tree[act.Package.ID] = make(map[string]any)
+		}
+		tree[act.Package.ID][act.Analyzer.Name] = struct {
+			Err string `json:"error"`
+		}{act.Err.Error()}
+	}
+	for _, f := range findings {
+		if tree[f.pkg] == nil {
+			tree[f.pkg] = make(map[string]any)
+		}
+		diags, _ := tree[f.pkg][f.analyzer].([]jsonDiagnostic)
+		tree[f.pkg][f.analyzer] = append(diags, toJSON(f))
+	}
+	data, err := json.MarshalIndent(tree, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func toJSON(f finding) jsonDiagnostic {
+	out := jsonDiagnostic{Category: f.diag.Category, Posn: f.posn.String(), Message: f.diag.Message}
+	for _, fix := range f.diag.SuggestedFixes {
+		jfix := jsonSuggestedFix{Message: fix.Message}
+		for _, edit := range fix.TextEdits {
+			file := f.fset.File(edit.Pos)
+			if file == nil {
+				continue
+			}
+			end := edit.End
+			if !end.IsValid() {
+				end = edit.Pos
+			}
+			jfix.Edits = append(jfix.Edits, jsonTextEdit{
+				Filename: file.Name(),
+				Start:    file.Offset(edit.Pos),
+				End:      file.Offset(end),
+				New:      string(edit.NewText),
+			})
+		}
+		out.SuggestedFixes = append(out.SuggestedFixes, jfix)
+	}
+	return out
+}
+
+func printSARIF(opts Options, findings []finding, problems []string) error {
+	out := make([]sarif.Finding, 0, len(findings))
+	for _, f := range findings {
+		jdiag := toJSON(f)
+		sf := sarif.Finding{
+			RuleID:   f.diag.Category,
+			Message:  f.diag.Message,
+			Filename: f.posn.Filename,
+			Line:     f.posn.Line,
+			Column:   f.posn.Column,
+		}
+		if f.diag.End.IsValid() {
+			end := f.fset.Position(f.diag.End)
+			sf.EndLine, sf.EndColumn = end.Line, end.Column
+		}
+		for _, fix := range jdiag.SuggestedFixes {
+			sfix := sarif.SuggestedFix{Message: fix.Message}
+			for _, edit := range fix.Edits {
+				sfix.Edits = append(sfix.Edits, sarif.TextEdit(edit))
+			}
+			sf.Fixes = append(sf.Fixes, sfix)
+		}
+		out = append(out, sf)
+	}
+	base := opts.Dir
+	if base == "" {
+		base = "."
+	}
+	log, err := sarif.New(out, sarif.Options{BaseDir: base, ConfigFile: opts.Config, Notifications: problems})
+	if err != nil {
+		return err
+	}
+	return log.Write(opts.Stdout)
+}
+
+func relativePath(dir, filename string) string {
+	if dir == "" {
+		dir = "."
+	}
+	base, err := filepath.Abs(dir)
+	if err != nil {
+		return filename
+	}
+	rel, err := filepath.Rel(base, filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filename
+	}
+	return rel
+}
@@ -0,0 +1,217 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/sarif"
+)
+
+const foldSource = `package sample
+
+import "strings"
+
+func Same(a, b string) bool {
+	return strings.ToLower(a) == strings.ToLower(b)
+}
+`
+
+const deferSource = `package sample
+
+func CloseAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		defer f.Close()
+	}
+}
+`
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/sample\n\ngo 1.22\n"
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func runIn(t *testing.T, dir string, opts Options) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	opts.Dir, opts.Stdout, opts.Stderr = dir, &stdout, &stderr
+	status := Run([]string{"./..."}, opts)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestRunReportsFindingsWithExitStatus(t *testing.T) {
+	dir := writeModule(t, map[string]string{"fold.go": foldSource, "loop.go": deferSource})
+
+	status, _, stderr := runIn(t, dir, Options{Tests: true})
+	require.Equal(t, ExitFindings, status)
+	require.Contains(t, stderr, "fold.go:6:9: [perf_equal_fold_compare]")
+	require.Contains(t, stderr, "loop.go:5:3: [perf_no_defer_in_loop]")
+	require.Equal(t, 2, strings.Count(stderr, "\n"), stderr)
+
+	clean := writeModule(t, map[string]string{"ok.go": "package sample\n\nfunc Ok() {}\n"})
+	status, _, stderr = runIn(t, clean, Options{})
+	require.Equal(t, ExitOK, status, stderr)
+}
+
+func TestRunJSONMatchesVetLayout(t *testing.T) {
+	dir := writeModule(t, map[string]string{"fold.go": foldSource})
+
+	status, stdout, _ := runIn(t, dir, Options{JSON: true})
+	require.Equal(t, ExitFindings, status)
+
+	var tree map[string]map[string][]jsonDiagnostic
+	require.NoError(t, json.Unmarshal([]byte(stdout), &tree))
+	diags := tree["example.com/sample"]["perf_equal_fold_compare"]
+	require.Len(t, diags, 1)
+	require.True(t, strings.HasSuffix(diags[0].Posn, "fold.go:6:9"))
+	require.Len(t, diags[0].SuggestedFixes, 1)
+}
+
+func TestRunSARIF(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"fold.go":         foldSource,
+		".perfcheck.yaml": "severity:\n  perf_equal_fold_compare: high\n",
+	})
+
+	status, stdout, _ := runIn(t, dir, Options{SARIF: true})
+	require.Equal(t, ExitFindings, status)
+	require.Contains(t, stdout, `"version": "2.1.0"`)
+	var log sarif.Log
+	require.NoError(t, json.Unmarshal([]byte(stdout), &log))
+	require.Len(t, log.Runs[0].Results, 1)
+	res := log.Runs[0].Results[0]
+	require.Equal(t, "perf_equal_fold_compare", res.RuleID)
+	require.Equal(t, "error", res.Level, "configured severity sets the level")
+	require.Contains(t, stdout, `"uri": "fold.go"`)
+	require.Contains(t, stdout, `"artifactChanges"`)
+}
+
+func TestRunDiffAndFix(t *testing.T) {
+	dir := writeModule(t, map[string]string{"fold.go": foldSource, "loop.go": deferSource})
+
+	status, stdout, stderr := runIn(t, dir, Options{Diff: true})
+	require.Equal(t, ExitFindings, status, "findings without fixes remain")
+	require.Contains(t, stdout, "+\treturn strings.EqualFold(a, b)")
+	require.Contains(t, stderr, "perf_equal_fold_compare", "-diff fixes nothing, so the finding remains")
+	src, err := os.ReadFile(filepath.Join(dir, "fold.go"))
+	require.NoError(t, err)
+	require.Equal(t, foldSource, string(src), "-diff must not write files")
+
+	status, _, _ = runIn(t, dir, Options{Fix: true})
+	require.Equal(t, ExitFindings, status)
+	src, err = os.ReadFile(filepath.Join(dir, "fold.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(src), "return strings.EqualFold(a, b)")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "loop.go")))
+	status, _, stderr = runIn(t, dir, Options{})
+	require.Equal(t, ExitOK, status, stderr)
+}
+
+func TestRunFixLeavesUntouchedCodeUnformatted(t *testing.T) {
+	src := `package sample
+
+import "strings"
+
+var table = map[string]int{
+	"a": 1,
+	"bbb":   2,
+}
+
+func Same(a, b string) bool {
+	return strings.ToLower(a) == strings.ToLower(b)
+}
+`
+	dir := writeModule(t, map[string]string{"fold.go": src})
+
+	_, stdout, _ := runIn(t, dir, Options{Diff: true})
+	require.Contains(t, stdout, "+\treturn strings.EqualFold(a, b)")
+	require.NotContains(t, stdout, "bbb", "-diff must show only the fixed lines")
+
+	status, _, stderr := runIn(t, dir, Options{Fix: true})
+	require.Equal(t, ExitOK, status, stderr)
+	fixed, err := os.ReadFile(filepath.Join(dir, "fold.go"))
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(src, "strings.ToLower(a) == strings.ToLower(b)", "strings.EqualFold(a, b)", 1),
+		string(fixed))
+}
+
+func TestRunDiffKeepsFixableFindingsFailing(t *testing.T) {
+	dir := writeModule(t, map[string]string{"fold.go": foldSource})
+
+	status, stdout, stderr := runIn(t, dir, Options{Diff: true})
+	require.Equal(t, ExitFindings, status, "-diff must not let a fixable finding pass")
+	require.Contains(t, stdout, "+\treturn strings.EqualFold(a, b)")
+	require.Contains(t, stderr, "fold.go:6:9: [perf_equal_fold_compare]")
+
+	status, _, stderr = runIn(t, dir, Options{Fix: true})
+	require.Equal(t, ExitOK, status, stderr)
+}
+
+func TestRunReportsLoadAndConfigErrors(t *testing.T) {
+	broken := writeModule(t, map[string]string{"bad.go": "package sample\n\nfunc Bad() { undefined() }\n"})
+	status, _, stderr := runIn(t, broken, Options{})
+	require.Equal(t, ExitError, status)
+	require.Contains(t, stderr, "undefined")
+
+	misconfigured := writeModule(t, map[string]string{
+		"fold.go":         foldSource,
+		".perfcheck.yaml": "rules:\n  exclude: [perf_nope]\n",
+	})
+	status, _, stderr = runIn(t, misconfigured, Options{})
+	require.Equal(t, ExitError, status)
+	require.Equal(t, 1, strings.Count(stderr, `unknown rule "perf_nope"`), stderr)
+}
+
+func TestMainRejectsBadUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, ExitUsage, Main(nil, &stdout, &stderr))
+	require.Equal(t, ExitUsage, Main([]string{"-json", "-sarif", "./..."}, &stdout, &stderr))
+	require.Equal(t, ExitUsage, Main([]string{"-ratchet", "./..."}, &stdout, &stderr))
+	require.Contains(t, stderr.String(), "-perf_prefer_stack_alloc.max_size")
+}
+
+func TestRunBaselineReportsOnlyNewFindings(t *testing.T) {
+	dir := writeModule(t, map[string]string{"fold.go": foldSource, "loop.go": deferSource})
+
+	status, _, stderr := runIn(t, dir, Options{Baseline: "baseline.json", WriteBaseline: true})
+	require.Equal(t, ExitOK, status, stderr)
+	require.Contains(t, stderr, "recorded 2 findings in baseline.json")
+
+	status, _, stderr = runIn(t, dir, Options{Baseline: "baseline.json"})
+	require.Equal(t, ExitOK, status, stderr)
+
+	// Shifting and reindenting known findings keeps them baselined, while a
+	// second copy of the same finding in another function is new.
+	shifted := strings.Replace(deferSource, "package sample\n", "package sample\n\n// CloseAll defers.\n", 1) +
+		"\nfunc CloseMore(files []interface{ Close() error }) {\n\tfor _, f := range files {\n\t\tdefer f.Close()\n\t}\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "loop.go"), []byte(shifted), 0o644))
+	status, _, stderr = runIn(t, dir, Options{Baseline: "baseline.json", Ratchet: true})
+	require.Equal(t, ExitFindings, status)
+	require.Equal(t, 1, strings.Count(stderr, "\n"), stderr)
+	require.Contains(t, stderr, "loop.go:13:3: [perf_no_defer_in_loop]")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "fold.go")))
+	status, _, stderr = runIn(t, dir, Options{Baseline: "baseline.json", Ratchet: true})
+	require.Equal(t, ExitFindings, status)
+	require.Contains(t, stderr, "lowered baseline baseline.json from 2 to 1 findings")
+
+	data, err := os.ReadFile(filepath.Join(dir, "baseline.json"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "perf_equal_fold_compare")
+	require.Contains(t, string(data), `"function": "CloseAll"`)
+}
@@ -0,0 +1,157 @@
+package driver
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+// applyFixes applies the first suggested fix of every finding, skipping fixes
+// that conflict with an already accepted one, and returns the findings that
+// stay unfixed. With opts.Diff the rewrites are printed instead of written,
+// so nothing is fixed and every finding is returned.
+func applyFixes(findings []finding, opts Options) ([]finding, error) {
+	accepted := make(map[string][]edit)
+	var remaining []finding
+	for _, f := range findings {
+		edits, ok := fixEdits(f)
+		if !ok || conflicts(accepted, edits) {
+			remaining = append(remaining, f)
+			continue
+		}
+		for name, list := range edits {
+			for _, e := range list {
+				if !contains(accepted[name], e) {
+					accepted[name] = append(accepted[name], e)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(accepted))
+	for name := range accepted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := rewriteFile(name, accepted[name], opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Diff {
+		return findings, nil
+	}
+	return remaining, nil
+}
+
+// fixEdits resolves the first suggested fix of f into byte-offset edits
+// grouped by file.
+func fixEdits(f finding) (map[string][]edit, bool) {
+	if len(f.diag.SuggestedFixes) == 0 {
+		return nil, false
+	}
+	edits := make(map[string][]edit)
+	for _, te := range f.diag.SuggestedFixes[0].TextEdits {
+		file := f.fset.File(te.Pos)
+		if file == nil {
+			return nil, false
+		}
+		end := te.End
+		if !end.IsValid() {
+			end = te.Pos
+		}
+		edits[file.Name()] = append(edits[file.Name()], edit{
+			start: file.Offset(te.Pos),
+			end:   file.Offset(end),
+			text:  string(te.NewText),
+		})
+	}
+	return edits, len(edits) > 0
+}
+
+func conflicts(accepted, edits map[string][]edit) bool {
+	for name, list := range edits {
+		for _, e := range list {
+			for _, other := range accepted[name] {
+				if e == other {
+					continue
+				}
+				if e.start < other.end && other.start < e.end {
+					return true
+				}
+				if e.start == other.start && (e.start == e.end || other.start == other.end) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func contains(list []edit, e edit) bool {
+	for _, other := range list {
+		if other == e {
+			return true
+		}
+	}
+	return false
+}
+
+func rewriteFile(name string, edits []edit, opts Options) error {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end < edits[j].end
+	})
+
+	var out bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		if e.start < last || e.end > len(src) {
+			return fmt.Errorf("%s: suggested fix edit out of range", name)
+		}
+		out.Write(src[last:e.start])
+		out.WriteString(e.text)
+		last = e.end
+	}
+	out.Write(src[last:])
+
+	// The edits are applied as they are: reformatting the whole file would
+	// also rewrite code no fix touched.
+	fixed := out.Bytes()
+
+	if opts.Diff {
+		rel := relativePath(opts.Dir, name)
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(src)),
+			B:        difflib.SplitLines(string(fixed)),
+			FromFile: rel + " (original)",
+			ToFile:   rel + " (fixed)",
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(opts.Stdout, diff)
+		return err
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, fixed, info.Mode().Perm())
+}
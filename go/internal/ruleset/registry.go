@@ -35,6 +35,7 @@ type Rule struct {
 	Severity       string
 	ProblemSummary string
 	FixHint        string
+	Params         map[string]string
 	Code           uint32
 }
 
@@ -143,6 +144,12 @@ func (r *Registry) RuleByID(id string) (Rule, bool) {
 	return rule, ok
 }
 
+// Param returns the registry default for the named tuning parameter.
+func (r Rule) Param(name string) (string, bool) {
+	value, ok := r.Params[name]
+	return value, ok
+}
+
 // RulesForLang returns a copy of the rules matching the provided language token.
 func (r *Registry) RulesForLang(lang string) []Rule {
 	lang = strings.ToLower(lang)
@@ -181,7 +188,7 @@ func parseTSV(data []byte) ([]Rule, error) {
 		}
 
 		fields := strings.Split(line, "\t")
-		if len(fields) != 7 {
+		if len(fields) != 7 && len(fields) != 8 {
 			return nil, errors.New("ruleset: invalid field count on line " + strconv.Itoa(lineNum))
 		}
 
@@ -192,6 +199,14 @@ func parseTSV(data []byte) ([]Rule, error) {
 			return nil, errors.New("ruleset: missing guidance fields on line " + strconv.Itoa(lineNum))
 		}
 
+		var params map[string]string
+		if len(fields) == 8 {
+			var err error
+			if params, err = parseParams(fields[7]); err != nil {
+				return nil, errors.New("ruleset: " + err.Error() + " on line " + strconv.Itoa(lineNum))
+			}
+		}
+
 		rules = append(rules, Rule{
 			ID:             strings.TrimSpace(fields[0]),
 			Langs:          langs,
@@ -200,6 +215,7 @@ func parseTSV(data []byte) ([]Rule, error) {
 			Severity:       strings.TrimSpace(fields[4]),
 			ProblemSummary: problem,
 			FixHint:        fix,
+			Params:         params,
 		})
 	}
 
@@ -210,6 +226,24 @@ func parseTSV(data []byte) ([]Rule, error) {
 	return rules, nil
 }
 
+// parseParams decodes the optional `key=value,key=value` tuning defaults.
+func parseParams(raw string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, errors.New("malformed param " + strconv.Quote(part))
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
 func parseLangs(raw string) []string {
 	if raw == "" {
 		return nil
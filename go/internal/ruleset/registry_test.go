@@ -49,3 +49,37 @@ func TestParseTSVRequiresGuidance(t *testing.T) {
 		t.Fatal("expected error when guidance fields missing")
 	}
 }
+
+func TestParseTSVParams(t *testing.T) {
+	data := "id\tlangs\tdescription\tcategory\tseverity\tproblem_summary\tfix_hint\tparams\n" +
+		"tuned\tgo\tdesc\tcat\twarning\twhy\tfix\tmax_size=64, window=2\n" +
+		"plain\tgo\tdesc\tcat\twarning\twhy\tfix\n"
+	rules, err := parseTSV([]byte(data))
+	if err != nil {
+		t.Fatalf("parseTSV unexpected error: %v", err)
+	}
+	if value, ok := rules[0].Param("max_size"); !ok || value != "64" {
+		t.Fatalf("max_size = %q, %v", value, ok)
+	}
+	if value, ok := rules[0].Param("window"); !ok || value != "2" {
+		t.Fatalf("window = %q, %v", value, ok)
+	}
+	if _, ok := rules[1].Param("max_size"); ok {
+		t.Fatal("rule without params column reported a param")
+	}
+
+	bad := "header\n" + "tuned\tgo\tdesc\tcat\twarning\twhy\tfix\tmax_size\n"
+	if _, err := parseTSV([]byte(bad)); err == nil {
+		t.Fatal("expected error for malformed param")
+	}
+}
+
+func TestDefaultRegistryParams(t *testing.T) {
+	rule, ok := MustDefault().RuleByID("perf_prefer_stack_alloc")
+	if !ok {
+		t.Fatal("perf_prefer_stack_alloc missing")
+	}
+	if value, ok := rule.Param("max_size"); !ok || value != "32" {
+		t.Fatalf("max_size = %q, %v", value, ok)
+	}
+}
@@ -0,0 +1,371 @@
+// Package sarif renders perfcheck diagnostics as SARIF 2.1.0 logs for code
+// scanning dashboards.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/pkg/perfchecklint"
+)
+
+const (
+	schemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	version   = "2.1.0"
+	srcRoot   = "%SRCROOT%"
+	toolURI   = "https://github.com/m-v-kalashnikov/perfcheck"
+)
+
+// Log is the top-level SARIF document.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run describes one invocation of perfcheck.
+type Run struct {
+	Tool               Tool                        `json:"tool"`
+	Invocations        []Invocation                `json:"invocations"`
+	OriginalURIBaseIDs map[string]ArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []Result                    `json:"results"`
+}
+
+// Tool wraps the driver component.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver identifies perfcheck and carries its rule descriptors.
+type Driver struct {
+	Name           string                `json:"name"`
+	InformationURI string                `json:"informationUri"`
+	Version        string                `json:"version,omitempty"`
+	Rules          []ReportingDescriptor `json:"rules"`
+}
+
+// ReportingDescriptor describes a single perfcheck rule.
+type ReportingDescriptor struct {
+	ID                   string         `json:"id"`
+	ShortDescription     Message        `json:"shortDescription"`
+	FullDescription      Message        `json:"fullDescription"`
+	Help                 Message        `json:"help"`
+	DefaultConfiguration Configuration  `json:"defaultConfiguration"`
+	Properties           map[string]any `json:"properties"`
+}
+
+// Configuration carries the default level of a rule.
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+// Invocation records whether the analysis completed and any tool problems.
+type Invocation struct {
+	ExecutionSuccessful        bool           `json:"executionSuccessful"`
+	ToolExecutionNotifications []Notification `json:"toolExecutionNotifications,omitempty"`
+}
+
+// Notification reports a problem running the tool rather than a finding.
+type Notification struct {
+	Level   string  `json:"level"`
+	Message Message `json:"message"`
+}
+
+// Message is a plain-text SARIF message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is one diagnostic.
+type Result struct {
+	RuleID    string     `json:"ruleId,omitempty"`
+	RuleIndex *int       `json:"ruleIndex,omitempty"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	Fixes     []Fix      `json:"fixes,omitempty"`
+}
+
+// Location wraps a physical source location.
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+// PhysicalLocation points at a region of a file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation names a file, relative to URIBaseID when set.
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region addresses text by line and column or by byte range.
+type Region struct {
+	StartLine   int  `json:"startLine,omitempty"`
+	StartColumn int  `json:"startColumn,omitempty"`
+	EndLine     int  `json:"endLine,omitempty"`
+	EndColumn   int  `json:"endColumn,omitempty"`
+	ByteOffset  *int `json:"byteOffset,omitempty"`
+	ByteLength  *int `json:"byteLength,omitempty"`
+}
+
+// Fix is a suggested fix expressed as artifact changes.
+type Fix struct {
+	Description     Message          `json:"description"`
+	ArtifactChanges []ArtifactChange `json:"artifactChanges"`
+}
+
+// ArtifactChange groups the replacements applied to one file.
+type ArtifactChange struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Replacements     []Replacement    `json:"replacements"`
+}
+
+// Replacement swaps a byte range for new content.
+type Replacement struct {
+	DeletedRegion   Region   `json:"deletedRegion"`
+	InsertedContent *Message `json:"insertedContent,omitempty"`
+}
+
+// Finding is a driver-neutral perfcheck diagnostic. RuleID comes from the
+// diagnostic category.
+type Finding struct {
+	RuleID    string
+	Message   string
+	Filename  string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	Fixes     []SuggestedFix
+}
+
+// SuggestedFix is a driver-neutral fix made of byte-offset edits.
+type SuggestedFix struct {
+	Message string
+	Edits   []TextEdit
+}
+
+// TextEdit replaces the half-open byte range [Start, End) of Filename.
+type TextEdit struct {
+	Filename string
+	Start    int
+	End      int
+	New      string
+}
+
+// Options tunes how a log is assembled.
+type Options struct {
+	// BaseDir makes file URIs relative to %SRCROOT%; absolute URIs are used
+	// for files outside it or when it is empty.
+	BaseDir string
+	// Version is reported as the driver version when set.
+	Version string
+	// ConfigFile names the configuration whose severities set result levels;
+	// by default each finding uses the one found above its file.
+	ConfigFile string
+	// Notifications lists tool failures, such as packages that did not
+	// type-check; any notification marks the invocation unsuccessful.
+	Notifications []string
+}
+
+// New builds a SARIF log whose rule table covers every Go rule in the
+// registry, so dashboards can describe rules even before they fire.
+func New(findings []Finding, opts Options) (*Log, error) {
+	rules, err := perfchecklint.Rules()
+	if err != nil {
+		return nil, err
+	}
+
+	driver := Driver{Name: "perfcheck", InformationURI: toolURI, Version: opts.Version}
+	index := make(map[string]int)
+	for _, rule := range rules {
+		if !slices.Contains(rule.Languages, "go") {
+			continue
+		}
+		index[rule.ID] = len(driver.Rules)
+		driver.Rules = append(driver.Rules, descriptor(rule))
+	}
+
+	base := ""
+	if opts.BaseDir != "" {
+		if base, err = filepath.Abs(opts.BaseDir); err != nil {
+			return nil, err
+		}
+	}
+
+	run := Run{
+		Tool:    Tool{Driver: driver},
+		Results: make([]Result, 0, len(findings)),
+	}
+	if base != "" {
+		run.OriginalURIBaseIDs = map[string]ArtifactLocation{srcRoot: {URI: fileURI(base) + "/"}}
+	}
+
+	invocation := Invocation{ExecutionSuccessful: len(opts.Notifications) == 0}
+	for _, text := range opts.Notifications {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications,
+			Notification{Level: "error", Message: Message{Text: text}})
+	}
+	run.Invocations = []Invocation{invocation}
+
+	configs := make(map[string]*perfchecklint.Config)
+	for _, finding := range findings {
+		res := result(finding, driver.Rules, index, base)
+		if _, ok := index[finding.RuleID]; ok {
+			cfg, err := findingConfig(configs, finding, base, opts.ConfigFile)
+			if err != nil {
+				return nil, err
+			}
+			res.Level = Level(cfg.Severity(finding.RuleID))
+		}
+		run.Results = append(run.Results, res)
+	}
+
+	return &Log{Schema: schemaURI, Version: version, Runs: []Run{run}}, nil
+}
+
+// Write encodes the log as indented JSON.
+func (l *Log) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(l)
+}
+
+func descriptor(rule perfchecklint.RuleMetadata) ReportingDescriptor {
+	return ReportingDescriptor{
+		ID:                   rule.ID,
+		ShortDescription:     Message{Text: rule.Description},
+		FullDescription:      Message{Text: rule.Summary},
+		Help:                 Message{Text: rule.Summary + " Fix: " + rule.Fix},
+		DefaultConfiguration: Configuration{Level: Level(rule.Severity)},
+		Properties: map[string]any{
+			"category": rule.Category,
+			"severity": rule.Severity,
+			"code":     rule.Code,
+			"tags":     []string{"performance", rule.Category},
+		},
+	}
+}
+
+// Level maps a perfcheck severity onto a SARIF result level.
+func Level(severity string) string {
+	switch strings.ToLower(severity) {
+	case "info":
+		return "note"
+	case "high", "error":
+		return "error"
+	default:
+		return "warning"
+	}
+}
+
+func result(finding Finding, rules []ReportingDescriptor, index map[string]int, base string) Result {
+	text := strings.TrimPrefix(finding.Message, "["+finding.RuleID+"] ")
+	res := Result{RuleID: finding.RuleID, Level: "warning", Message: Message{Text: text}}
+	if i, ok := index[finding.RuleID]; ok {
+		res.RuleIndex = &i
+		res.Level = rules[i].DefaultConfiguration.Level
+	}
+
+	if finding.Filename != "" {
+		loc := PhysicalLocation{ArtifactLocation: artifact(finding.Filename, base)}
+		if finding.Line > 0 {
+			loc.Region = &Region{
+				StartLine:   finding.Line,
+				StartColumn: finding.Column,
+				EndLine:     finding.EndLine,
+				EndColumn:   finding.EndColumn,
+			}
+		}
+		res.Locations = []Location{{PhysicalLocation: loc}}
+	}
+
+	for _, fix := range finding.Fixes {
+		res.Fixes = append(res.Fixes, sarifFix(fix, base))
+	}
+	return res
+}
+
+func sarifFix(fix SuggestedFix, base string) Fix {
+	out := Fix{Description: Message{Text: fix.Message}}
+	byFile := make(map[string]int)
+	for _, edit := range fix.Edits {
+		i, ok := byFile[edit.Filename]
+		if !ok {
+			i = len(out.ArtifactChanges)
+			byFile[edit.Filename] = i
+			out.ArtifactChanges = append(out.ArtifactChanges,
+				ArtifactChange{ArtifactLocation: artifact(edit.Filename, base)})
+		}
+		offset, length := edit.Start, edit.End-edit.Start
+		replacement := Replacement{DeletedRegion: Region{ByteOffset: &offset, ByteLength: &length}}
+		if edit.New != "" {
+			replacement.InsertedContent = &Message{Text: edit.New}
+		}
+		out.ArtifactChanges[i].Replacements = append(out.ArtifactChanges[i].Replacements, replacement)
+	}
+	return out
+}
+
+// findingConfig resolves the configuration for the directory of finding,
+// caching it per directory.
+func findingConfig(
+	configs map[string]*perfchecklint.Config,
+	finding Finding,
+	base, filename string,
+) (*perfchecklint.Config, error) {
+	dir := ""
+	if finding.Filename != "" {
+		dir = filepath.Dir(finding.Filename)
+		if !filepath.IsAbs(dir) && base != "" {
+			dir = filepath.Join(base, dir)
+		}
+	}
+	if cfg, ok := configs[dir]; ok {
+		return cfg, nil
+	}
+	cfg, err := perfchecklint.ResolveConfig(dir, filename)
+	if err != nil {
+		return nil, err
+	}
+	configs[dir] = cfg
+	return cfg, nil
+}
+
+func artifact(filename, base string) ArtifactLocation {
+	abs := filename
+	if !filepath.IsAbs(abs) && base != "" {
+		abs = filepath.Join(base, abs)
+	}
+	if base != "" {
+		if rel, err := filepath.Rel(base, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return ArtifactLocation{URI: escapePath(filepath.ToSlash(rel)), URIBaseID: srcRoot}
+		}
+	}
+	if filepath.IsAbs(abs) {
+		return ArtifactLocation{URI: fileURI(abs)}
+	}
+	return ArtifactLocation{URI: escapePath(filepath.ToSlash(abs))}
+}
+
+func fileURI(abs string) string {
+	path := filepath.ToSlash(abs)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
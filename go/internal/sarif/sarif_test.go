@@ -0,0 +1,168 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/pkg/perfchecklint"
+)
+
+const vetOutput = `# example.com/app
+{
+	"example.com/app": {
+		"perf_equal_fold_compare": [
+			{
+				"category": "perf_equal_fold_compare",
+				"posn": "/src/app/main.go:12:5",
+				"message": "[perf_equal_fold_compare] use strings.EqualFold. Why: w. Fix: f.",
+				"suggested_fixes": [
+					{
+						"message": "Replace with strings.EqualFold",
+						"edits": [
+							{"filename": "/src/app/main.go", "start": 120, "end": 136, "new": "strings.EqualFold("},
+							{"filename": "/src/app/main.go", "start": 150, "end": 151, "new": ""}
+						]
+					}
+				]
+			}
+		],
+		"perf_use_buffered_io": []
+	}
+}
+vet: app/broken.go:3:1: undefined: missing
+# example.com/app/sub
+{
+	"example.com/app/sub": {
+		"perf_prefer_stack_alloc": {
+			"error": "max_size must be positive, got 0"
+		}
+	}
+}
+`
+
+func TestParseVetJSON(t *testing.T) {
+	findings, notifications, err := ParseVetJSON(strings.NewReader(vetOutput))
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	require.Equal(t, []string{
+		"vet: app/broken.go:3:1: undefined: missing",
+		"example.com/app/sub: perf_prefer_stack_alloc: max_size must be positive, got 0",
+	}, notifications)
+
+	finding := findings[0]
+	require.Equal(t, "perf_equal_fold_compare", finding.RuleID)
+	require.Equal(t, "/src/app/main.go", finding.Filename)
+	require.Equal(t, 12, finding.Line)
+	require.Equal(t, 5, finding.Column)
+	require.Len(t, finding.Fixes, 1)
+	require.Len(t, finding.Fixes[0].Edits, 2)
+
+	_, _, err = ParseVetJSON(strings.NewReader("{\n\t\"pkg\": {\n"))
+	require.Error(t, err)
+}
+
+func TestNewBuildsRulesResultsAndFixes(t *testing.T) {
+	findings, notifications, err := ParseVetJSON(strings.NewReader(vetOutput))
+	require.NoError(t, err)
+
+	base := filepath.FromSlash("/src")
+	log, err := New(findings, Options{BaseDir: base, Version: "v1.2.3", Notifications: notifications})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, log.Write(&buf))
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, "2.1.0", decoded["version"])
+
+	run := log.Runs[0]
+	require.False(t, run.Invocations[0].ExecutionSuccessful)
+	require.Len(t, run.Invocations[0].ToolExecutionNotifications, 2)
+	require.Equal(t, "v1.2.3", run.Tool.Driver.Version)
+
+	var equalFold *ReportingDescriptor
+	for i := range run.Tool.Driver.Rules {
+		rule := &run.Tool.Driver.Rules[i]
+		require.NotEqual(t, "perf_borrow_instead_of_clone", rule.ID, "rust-only rules are omitted")
+		if rule.ID == "perf_equal_fold_compare" {
+			equalFold = rule
+		}
+	}
+	require.NotNil(t, equalFold)
+	require.Equal(t, "note", equalFold.DefaultConfiguration.Level)
+	require.Contains(t, equalFold.Help.Text, " Fix: ")
+	require.NotZero(t, equalFold.Properties["code"])
+
+	require.Len(t, run.Results, 1)
+	res := run.Results[0]
+	require.Equal(t, "perf_equal_fold_compare", res.RuleID)
+	require.Equal(t, "perf_equal_fold_compare", run.Tool.Driver.Rules[*res.RuleIndex].ID)
+	require.Equal(t, "note", res.Level)
+	require.Equal(t, "use strings.EqualFold. Why: w. Fix: f.", res.Message.Text)
+
+	loc := res.Locations[0].PhysicalLocation
+	require.Equal(t, "app/main.go", loc.ArtifactLocation.URI)
+	require.Equal(t, "%SRCROOT%", loc.ArtifactLocation.URIBaseID)
+	require.Equal(t, 12, loc.Region.StartLine)
+
+	require.Len(t, res.Fixes, 1)
+	change := res.Fixes[0].ArtifactChanges
+	require.Len(t, change, 1)
+	require.Len(t, change[0].Replacements, 2)
+	first := change[0].Replacements[0]
+	require.Equal(t, 120, *first.DeletedRegion.ByteOffset)
+	require.Equal(t, 16, *first.DeletedRegion.ByteLength)
+	require.Equal(t, "strings.EqualFold(", first.InsertedContent.Text)
+	require.Nil(t, change[0].Replacements[1].InsertedContent)
+}
+
+func TestNewTakesLevelsFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(perfchecklint.ConfigEnv, "")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".perfcheck.yaml"),
+		[]byte("severity:\n  perf_equal_fold_compare: error\n"), 0o644))
+	findings := []Finding{{
+		RuleID:   "perf_equal_fold_compare",
+		Message:  "[perf_equal_fold_compare] use strings.EqualFold. Why: w. Fix: f.",
+		Filename: filepath.Join(dir, "main.go"),
+		Line:     3,
+	}}
+
+	log, err := New(findings, Options{BaseDir: dir})
+	require.NoError(t, err)
+	res := log.Runs[0].Results[0]
+	require.Equal(t, "error", res.Level, "configured severity overrides the rule default")
+	require.Equal(t, "use strings.EqualFold. Why: w. Fix: f.", res.Message.Text)
+
+	pinned := filepath.Join(t.TempDir(), "perfcheck.yaml")
+	require.NoError(t, os.WriteFile(pinned, []byte("severity:\n  perf_equal_fold_compare: info\n"), 0o644))
+	log, err = New(findings, Options{BaseDir: dir, ConfigFile: pinned})
+	require.NoError(t, err)
+	require.Equal(t, "note", log.Runs[0].Results[0].Level)
+}
+
+func TestLevelMapping(t *testing.T) {
+	require.Equal(t, "note", Level("info"))
+	require.Equal(t, "warning", Level("warning"))
+	require.Equal(t, "warning", Level("medium"))
+	require.Equal(t, "error", Level("high"))
+	require.Equal(t, "error", Level("ERROR"))
+}
+
+func TestSplitPosn(t *testing.T) {
+	file, line, col := splitPosn(`C:\src\a.go:3:7`)
+	require.Equal(t, `C:\src\a.go`, file)
+	require.Equal(t, 3, line)
+	require.Equal(t, 7, col)
+
+	file, line, col = splitPosn("a.go:3")
+	require.Equal(t, "a.go", file)
+	require.Equal(t, 3, line)
+	require.Zero(t, col)
+}
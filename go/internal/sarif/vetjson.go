@@ -0,0 +1,155 @@
+package sarif
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// vetDiagnostic mirrors the diagnostic objects printed by `go vet -json`.
+type vetDiagnostic struct {
+	Category       string `json:"category"`
+	Posn           string `json:"posn"`
+	Message        string `json:"message"`
+	SuggestedFixes []struct {
+		Message string `json:"message"`
+		Edits   []struct {
+			Filename string `json:"filename"`
+			Start    int    `json:"start"`
+			End      int    `json:"end"`
+			New      string `json:"new"`
+		} `json:"edits"`
+	} `json:"suggested_fixes"`
+}
+
+// ParseVetJSON reads the stream written by `go vet -vettool=perfcheck-go
+// -json`: "# package" comment lines followed by one JSON tree per package.
+// Analyzer errors and any non-JSON output (for example type-check failures)
+// are returned as notifications so the SARIF log records the broken run.
+func ParseVetJSON(r io.Reader) ([]Finding, []string, error) {
+	var (
+		findings      []Finding
+		notifications []string
+		block         strings.Builder
+		inBlock       bool
+		lineNum       int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		switch {
+		case inBlock:
+			block.WriteString(line)
+			block.WriteByte('\n')
+			if line != "}" {
+				continue
+			}
+			inBlock = false
+			found, failed, err := decodeVetTree([]byte(block.String()))
+			if err != nil {
+				return nil, nil, fmt.Errorf("vet json: line %d: %w", lineNum, err)
+			}
+			findings = append(findings, found...)
+			notifications = append(notifications, failed...)
+			block.Reset()
+		case line == "{":
+			inBlock = true
+			block.WriteString(line)
+			block.WriteByte('\n')
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#"):
+		default:
+			notifications = append(notifications, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if inBlock {
+		return nil, nil, fmt.Errorf("vet json: unterminated object at end of input")
+	}
+	return findings, notifications, nil
+}
+
+func decodeVetTree(data []byte) ([]Finding, []string, error) {
+	var tree map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, nil, err
+	}
+
+	var findings []Finding
+	var notifications []string
+	for _, pkg := range sortedKeys(tree) {
+		for _, analyzer := range sortedKeys(tree[pkg]) {
+			raw := tree[pkg][analyzer]
+			var failure struct {
+				Err string `json:"error"`
+			}
+			if json.Unmarshal(raw, &failure) == nil && failure.Err != "" {
+				notifications = append(notifications, fmt.Sprintf("%s: %s: %s", pkg, analyzer, failure.Err))
+				continue
+			}
+			var diags []vetDiagnostic
+			if err := json.Unmarshal(raw, &diags); err != nil {
+				return nil, nil, fmt.Errorf("%s: %s: %w", pkg, analyzer, err)
+			}
+			for _, diag := range diags {
+				findings = append(findings, vetFinding(diag))
+			}
+		}
+	}
+	return findings, notifications, nil
+}
+
+func vetFinding(diag vetDiagnostic) Finding {
+	finding := Finding{RuleID: diag.Category, Message: diag.Message}
+	finding.Filename, finding.Line, finding.Column = splitPosn(diag.Posn)
+	for _, fix := range diag.SuggestedFixes {
+		out := SuggestedFix{Message: fix.Message}
+		for _, edit := range fix.Edits {
+			out.Edits = append(out.Edits, TextEdit{
+				Filename: edit.Filename,
+				Start:    edit.Start,
+				End:      edit.End,
+				New:      edit.New,
+			})
+		}
+		finding.Fixes = append(finding.Fixes, out)
+	}
+	return finding
+}
+
+// splitPosn parses token.Position strings: "file:line:col", "file:line", or
+// a bare file name. The file part may itself contain colons.
+func splitPosn(posn string) (string, int, int) {
+	if posn == "" || posn == "-" {
+		return "", 0, 0
+	}
+	file, line, col := posn, 0, 0
+	if i := strings.LastIndexByte(file, ':'); i >= 0 {
+		if n, err := strconv.Atoi(file[i+1:]); err == nil {
+			file, line = file[:i], n
+			if j := strings.LastIndexByte(file, ':'); j >= 0 {
+				if m, err := strconv.Atoi(file[j+1:]); err == nil {
+					file, line, col = file[:j], m, line
+				}
+			}
+		}
+	}
+	return file, line, col
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
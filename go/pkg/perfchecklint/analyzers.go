@@ -1,10 +1,30 @@
 // Package perfchecklint hosts perfcheck-specific static analysis detectors.
 package perfchecklint
 
-import "golang.org/x/tools/go/analysis"
+import (
+	"slices"
+	"strconv"
 
-// Analyzers returns the analyzers implemented by perfcheck.
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// Analyzers returns the analyzers implemented by perfcheck, including the
+// audit of //perfcheck:ignore directives.
 func Analyzers() []*analysis.Analyzer {
+	return append(ruleAnalyzers(), ignoreDirectiveAnalyzer)
+}
+
+// FactAnalyzers returns the analyzers that export facts about dependencies.
+// Drivers that analyze dependencies only for their facts, like go vet, need
+// to run just these there.
+func FactAnalyzers() []*analysis.Analyzer {
+	return []*analysis.Analyzer{callCostAnalyzer}
+}
+
+// ruleAnalyzers returns the analyzers that report performance rules.
+func ruleAnalyzers() []*analysis.Analyzer {
 	return []*analysis.Analyzer{
 		stringConcatLoopAnalyzer,
 		regexCompileLoopAnalyzer,
@@ -20,7 +40,112 @@ func Analyzers() []*analysis.Analyzer {
 		runeConversionAnalyzer,
 		bufferedIOAnalyzer,
 		stackAllocAnalyzer,
+		mapKeyConversionAnalyzer,
+		loopInvariantCallAnalyzer,
+		syscallLoopAnalyzer,
+		preferStrconvAnalyzer,
+		builderGrowAnalyzer,
+		timerReuseAnalyzer,
+		lockBlockingAnalyzer,
+		rangeCopyAnalyzer,
+		largeParamAnalyzer,
+		interfaceBoxingAnalyzer,
+		codecLoopAnalyzer,
+		httpClientAnalyzer,
+		sqlLoopAnalyzer,
+	}
+}
+
+// analyzerRules maps every reporting analyzer to the registry rule it emits so
+// drivers can drop analyzers that a configuration disables.
+var analyzerRules = map[*analysis.Analyzer]string{
+	stringConcatLoopAnalyzer:       "perf_avoid_string_concat_loop",
+	regexCompileLoopAnalyzer:       "perf_regex_compile_once",
+	preallocateCollectionsAnalyzer: "perf_preallocate_collections",
+	reflectionLoopAnalyzer:         "perf_avoid_reflection_dynamic",
+	boundConcurrencyAnalyzer:       "perf_bound_concurrency",
+	equalFoldAnalyzer:              "perf_equal_fold_compare",
+	syncPoolPointerAnalyzer:        "perf_syncpool_store_pointers",
+	writerPreferBytesAnalyzer:      "perf_writer_prefer_bytes",
+	linkedListAnalyzer:             "perf_avoid_linked_list",
+	atomicSmallLockAnalyzer:        "perf_atomic_for_small_lock",
+	deferInLoopAnalyzer:            "perf_no_defer_in_loop",
+	runeConversionAnalyzer:         "perf_avoid_rune_conversion",
+	bufferedIOAnalyzer:             "perf_use_buffered_io",
+	stackAllocAnalyzer:             "perf_prefer_stack_alloc",
+	mapKeyConversionAnalyzer:       "perf_inline_byte_to_string_mapkey",
+	loopInvariantCallAnalyzer:      "perf_hoist_loop_invariant_call",
+	syscallLoopAnalyzer:            "perf_batch_syscalls_cgo",
+	preferStrconvAnalyzer:          "perf_prefer_strconv",
+	builderGrowAnalyzer:            "perf_builder_grow",
+	timerReuseAnalyzer:             "perf_reuse_timers",
+	lockBlockingAnalyzer:           "perf_no_blocking_under_lock",
+	rangeCopyAnalyzer:              "perf_range_value_copy",
+	largeParamAnalyzer:             "perf_large_param_copy",
+	interfaceBoxingAnalyzer:        "perf_avoid_interface_boxing",
+	codecLoopAnalyzer:              "perf_avoid_codec_reflection_loop",
+	httpClientAnalyzer:             "perf_reuse_http_client",
+	sqlLoopAnalyzer:                "perf_avoid_n_plus_one_query",
+	ignoreDirectiveAnalyzer:        "perf_ignore_directive",
+}
+
+// bundleAnalyzers returns copies of the analyzers cfg enables, wired to
+// configuration and profile analyzers bound to opts so that bundles never
+// share state. The directive audit waits for every rule analyzer it requires,
+// so the bundle's audit requires only the enabled rules; otherwise the driver
+// would pull the disabled ones back in.
+func bundleAnalyzers(opts BuildOptions, cfg *Config) []*analysis.Analyzer {
+	config := newConfigAnalyzer(opts.Config, opts.ConfigFile)
+	profile := newProfileAnalyzer(config, opts.ProfileFile)
+	bind := func(analyzer *analysis.Analyzer) *analysis.Analyzer {
+		bound := *analyzer
+		bound.Requires = make([]*analysis.Analyzer, len(analyzer.Requires))
+		for i, req := range analyzer.Requires {
+			switch req {
+			case configAnalyzer:
+				req = config
+			case profileAnalyzer:
+				req = profile
+			}
+			bound.Requires[i] = req
+		}
+		return &bound
+	}
+
+	var rules []*analysis.Analyzer
+	for _, analyzer := range ruleAnalyzers() {
+		if cfg.Enabled(analyzerRules[analyzer]) {
+			rules = append(rules, bind(analyzer))
+		}
+	}
+	if !cfg.Enabled(analyzerRules[ignoreDirectiveAnalyzer]) {
+		return rules
+	}
+	audit := *ignoreDirectiveAnalyzer
+	audit.Requires = append(slices.Clone(rules), directivesAnalyzer, config)
+	return append(rules, &audit)
+}
+
+// ruleIntParam returns the registry default for an integer tuning flag,
+// falling back when the registry omits or mistypes the parameter.
+func ruleIntParam(ruleID, name string, fallback int) int {
+	reg, err := ruleset.Default()
+	if err != nil {
+		return fallback
+	}
+	rule, ok := reg.RuleByID(ruleID)
+	if !ok {
+		return fallback
+	}
+	raw, ok := rule.Param(name)
+	if !ok {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return fallback
 	}
+	return value
 }
 
 // All is a deprecated alias for Analyzers kept for transitional callers.
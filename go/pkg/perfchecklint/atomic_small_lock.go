@@ -13,16 +13,29 @@ import (
 	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
 )
 
+// atomicLockMaxStatements bounds how many statements may sit between Lock and
+// Unlock for the critical section to count as a single primitive update.
+var atomicLockMaxStatements = ruleIntParam("perf_atomic_for_small_lock", "max_statements", 1)
+
+func init() {
+	atomicSmallLockAnalyzer.Flags.IntVar(&atomicLockMaxStatements, "max_statements", atomicLockMaxStatements,
+		"most statements between Lock and Unlock that may all update one primitive")
+}
+
 var atomicSmallLockAnalyzer = &analysis.Analyzer{
 	Name:     "perf_atomic_for_small_lock",
 	Doc:      "reports mutexes guarding single primitive values",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_atomic_for_small_lock")
 		if !ok {
 			return nil, fmt.Errorf("rule perf_atomic_for_small_lock not found")
 		}
 
+		if atomicLockMaxStatements <= 0 {
+			return nil, fmt.Errorf("max_statements must be positive, got %d", atomicLockMaxStatements)
+		}
+
 		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 		if ins == nil {
 			return nil, fmt.Errorf("missing inspector dependency")
@@ -59,46 +72,71 @@ func analyzeMutexSections(pass *analysis.Pass, body *ast.BlockStmt, rule ruleset
 			continue
 		}
 
-		if i+2 >= len(stmts) {
-			continue
-		}
-		subject, ok := primitiveMutation(pass, stmts[i+1])
-		if !ok {
-			continue
-		}
-		if !isUnlockCall(pass, stmts[i+2], receiver) {
+		subject, unlock := primitiveCriticalSection(pass, stmts[i+1:], receiver)
+		if unlock < 0 {
 			continue
 		}
 		msg := fmt.Sprintf("mutex %s guards primitive %s; use sync/atomic", receiver.expr, subject)
 		report(pass, stmts[i+1].Pos(), rule, msg)
-		i += 2
+		i += unlock + 1
 	}
 }
 
+// primitiveCriticalSection reports the primitive updated by every statement
+// before the matching Unlock and the Unlock's index in stmts, or -1 when the
+// section exceeds the statement window or touches anything else.
+func primitiveCriticalSection(pass *analysis.Pass, stmts []ast.Stmt, receiver mutexTarget) (string, int) {
+	subject := ""
+	for j, stmt := range stmts {
+		if j > atomicLockMaxStatements {
+			break
+		}
+		if isUnlockCall(pass, stmt, receiver) {
+			if subject == "" {
+				break
+			}
+			return subject, j
+		}
+		target, ok := primitiveMutation(pass, stmt)
+		if !ok || (subject != "" && target != subject) {
+			break
+		}
+		subject = target
+	}
+	return "", -1
+}
+
 func lockCallReceiver(pass *analysis.Pass, stmt ast.Stmt) (mutexTarget, bool) {
+	receiver, method, ok := mutexLockCall(pass, stmt)
+	return receiver, ok && method == "Lock"
+}
+
+// mutexLockCall reports the mutex stmt acquires with Lock or RLock, and which
+// of the two it calls.
+func mutexLockCall(pass *analysis.Pass, stmt ast.Stmt) (mutexTarget, string, bool) {
 	exprStmt, ok := stmt.(*ast.ExprStmt)
 	if !ok {
-		return mutexTarget{}, false
+		return mutexTarget{}, "", false
 	}
 	call, ok := exprStmt.X.(*ast.CallExpr)
 	if !ok {
-		return mutexTarget{}, false
+		return mutexTarget{}, "", false
 	}
 	sel, ok := call.Fun.(*ast.SelectorExpr)
 	if !ok {
-		return mutexTarget{}, false
+		return mutexTarget{}, "", false
 	}
 	selInfo := pass.TypesInfo.Selections[sel]
 	if selInfo == nil {
-		return mutexTarget{}, false
+		return mutexTarget{}, "", false
 	}
-	if sel.Sel == nil || sel.Sel.Name != "Lock" {
-		return mutexTarget{}, false
+	if sel.Sel == nil || (sel.Sel.Name != "Lock" && sel.Sel.Name != "RLock") {
+		return mutexTarget{}, "", false
 	}
 	if !isSyncMutex(selInfo.Recv()) {
-		return mutexTarget{}, false
+		return mutexTarget{}, "", false
 	}
-	return makeMutexTarget(pass, sel.X), true
+	return makeMutexTarget(pass, sel.X), sel.Sel.Name, true
 }
 
 func isUnlockCall(pass *analysis.Pass, stmt ast.Stmt, receiver mutexTarget) bool {
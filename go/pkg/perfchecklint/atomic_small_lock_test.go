@@ -53,3 +53,40 @@ func (c *counter) log() {}
 		t.Fatalf("expected no diagnostics, got %d", len(diags))
 	}
 }
+
+func TestAtomicSmallLockAnalyzerHonorsMaxStatementsFlag(t *testing.T) {
+	src := `package sample
+
+import "sync"
+
+type counter struct {
+	mu  sync.Mutex
+	val int
+	hits int
+}
+
+func (c *counter) bump(v int) {
+	c.mu.Lock()
+	c.val += v
+	c.val++
+	c.mu.Unlock()
+}
+
+func (c *counter) both(v int) {
+	c.mu.Lock()
+	c.val += v
+	c.hits++
+	c.mu.Unlock()
+}
+`
+
+	if diags := runAnalyzerOnSource(t, atomicSmallLockAnalyzer, "atomic_window.go", src); len(diags) != 0 {
+		t.Fatalf("expected default window to skip two-statement sections, got %d", len(diags))
+	}
+
+	setAnalyzerFlag(t, atomicSmallLockAnalyzer, "max_statements", "2")
+	diags := runAnalyzerOnSource(t, atomicSmallLockAnalyzer, "atomic_window.go", src)
+	if len(diags) != 1 {
+		t.Fatalf("expected only the single-primitive section to be reported, got %d", len(diags))
+	}
+}
@@ -12,9 +12,11 @@ import (
 )
 
 var boundConcurrencyAnalyzer = &analysis.Analyzer{
-	Name:     "perf_bound_concurrency",
-	Doc:      "reports unbounded goroutine creation inside loops",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Name: "perf_bound_concurrency",
+	Doc:  "reports unbounded goroutine creation inside loops",
+	Requires: []*analysis.Analyzer{
+		inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer, callCostAnalyzer,
+	},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_bound_concurrency")
 		if !ok {
@@ -46,6 +48,8 @@ var boundConcurrencyAnalyzer = &analysis.Analyzer{
 			}
 		})
 
+		reportCostlyCalls(pass, ins, costGoroutine, rule)
+
 		return nil, nil
 	},
 }
@@ -14,16 +14,29 @@ import (
 	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
 )
 
+// bufferedIOMaxPayload is the largest literal payload, in bytes or runes,
+// treated as a tiny unbuffered write.
+var bufferedIOMaxPayload = ruleIntParam("perf_use_buffered_io", "max_payload", 4)
+
+func init() {
+	bufferedIOAnalyzer.Flags.IntVar(&bufferedIOMaxPayload, "max_payload", bufferedIOMaxPayload,
+		"largest literal payload in bytes treated as a tiny unbuffered write")
+}
+
 var bufferedIOAnalyzer = &analysis.Analyzer{
 	Name:     "perf_use_buffered_io",
 	Doc:      "reports repeated small I/O without buffering",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_use_buffered_io")
 		if !ok {
 			return nil, fmt.Errorf("rule perf_use_buffered_io not found")
 		}
 
+		if bufferedIOMaxPayload <= 0 {
+			return nil, fmt.Errorf("max_payload must be positive, got %d", bufferedIOMaxPayload)
+		}
+
 		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 		if ins == nil {
 			return nil, fmt.Errorf("missing inspector dependency")
@@ -163,7 +176,13 @@ func isBufferedWriterType(typ types.Type) bool {
 		}
 		return false
 	default:
-		return isBufferedWriterType(t.Underlying())
+		// Unnamed types and type parameters may be their own underlying
+		// type; stop there instead of recursing forever.
+		u := t.Underlying()
+		if u == t {
+			return false
+		}
+		return isBufferedWriterType(u)
 	}
 }
 
@@ -181,13 +200,14 @@ func isSmallBytePayload(pass *analysis.Pass, expr ast.Expr) bool {
 	}
 	switch v := expr.(type) {
 	case *ast.CompositeLit:
-		return len(v.Elts) > 0 && len(v.Elts) <= 4
+		return len(v.Elts) > 0 && len(v.Elts) <= bufferedIOMaxPayload
 	case *ast.CallExpr:
 		return isSmallMakeSlice(v)
 	case *ast.BasicLit:
 		if v.Kind == token.STRING {
 			if unquoted, err := strconv.Unquote(v.Value); err == nil {
-				return len([]rune(unquoted)) > 0 && len([]rune(unquoted)) <= 4
+				n := len([]rune(unquoted))
+				return n > 0 && n <= bufferedIOMaxPayload
 			}
 		}
 	}
@@ -214,19 +234,19 @@ func isSmallMakeSlice(call *ast.CallExpr) bool {
 	if !ok || sizeLit.Kind != token.INT {
 		return false
 	}
-	return parseSmallInt(sizeLit.Value)
+	return parseSmallInt(sizeLit.Value, bufferedIOMaxPayload)
 }
 
-func parseSmallInt(text string) bool {
+func parseSmallInt(text string, limit int) bool {
 	var value int
 	for _, ch := range text {
 		if ch < '0' || ch > '9' {
 			return false
 		}
 		value = value*10 + int(ch-'0')
-		if value > 4 {
+		if value > limit {
 			return false
 		}
 	}
-	return value > 0 && value <= 4
+	return value > 0 && value <= limit
 }
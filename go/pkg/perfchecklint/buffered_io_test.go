@@ -61,3 +61,46 @@ func writeAll(path string, payload []byte) error {
 		t.Fatalf("expected no diagnostics, got %d", len(diags))
 	}
 }
+
+func TestBufferedIOAnalyzerHonorsMaxPayloadFlag(t *testing.T) {
+	src := `package sample
+
+import "os"
+
+func emit(f *os.File, n int) {
+	for i := 0; i < n; i++ {
+		f.Write([]byte{1, 2, 3, 4, 5})
+		f.Write(make([]byte, 8))
+	}
+}
+`
+
+	if diags := runAnalyzerOnSource(t, bufferedIOAnalyzer, "buffered_io_payload.go", src); len(diags) != 0 {
+		t.Fatalf("expected payloads above 4 bytes to pass, got %d diagnostics", len(diags))
+	}
+
+	setAnalyzerFlag(t, bufferedIOAnalyzer, "max_payload", "8")
+	diags := runAnalyzerOnSource(t, bufferedIOAnalyzer, "buffered_io_payload.go", src)
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics with max_payload=8, got %d", len(diags))
+	}
+}
+
+func TestBufferedIOAnalyzerHandlesUnnamedAndGenericWriters(t *testing.T) {
+	src := `package sample
+
+import "fmt"
+
+func emit[W interface{ Write([]byte) (int, error) }](w W, out interface{ Write([]byte) (int, error) }, n int) {
+	for range n {
+		fmt.Fprint(w, "x")
+		fmt.Fprint(out, "x")
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, bufferedIOAnalyzer, "generic_writer.go", src)
+	if len(diags) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %d", len(diags))
+	}
+}
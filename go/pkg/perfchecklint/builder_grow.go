@@ -0,0 +1,540 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"go/version"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// builderGrowMinWrites is the number of straight-line writes of known total
+// size that warrant growing a builder up front.
+var builderGrowMinWrites = ruleIntParam("perf_builder_grow", "min_writes", 3)
+
+func init() {
+	builderGrowAnalyzer.Flags.IntVar(&builderGrowMinWrites, "min_writes", builderGrowMinWrites,
+		"straight-line writes of known total size that warrant an up-front Grow")
+}
+
+var builderGrowAnalyzer = &analysis.Analyzer{
+	Name:     "perf_builder_grow",
+	Doc:      "reports strings.Builder and bytes.Buffer values filled to a predictable size without Grow",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_builder_grow")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_builder_grow not found")
+		}
+
+		if builderGrowMinWrites <= 0 {
+			return nil, fmt.Errorf("min_writes must be positive, got %d", builderGrowMinWrites)
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		ins.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(node ast.Node) {
+			var body *ast.BlockStmt
+			switch fn := node.(type) {
+			case *ast.FuncDecl:
+				body = fn.Body
+			case *ast.FuncLit:
+				body = fn.Body
+			}
+			if body == nil {
+				return
+			}
+			for _, buf := range growableBuffers(pass, body) {
+				checkBufferGrowth(pass, rule, body, buf)
+			}
+		})
+
+		return nil, nil
+	},
+}
+
+// growableBuffer is a strings.Builder or bytes.Buffer that a function
+// declares empty, along with every later reference to it in that function.
+type growableBuffer struct {
+	v       *types.Var
+	typ     string
+	decl    ast.Stmt
+	block   []ast.Stmt
+	uses    []bufferUse
+	escaped bool
+}
+
+// bufferUse is one reference to a growable buffer. method names the method
+// called on it, and is empty when the buffer is used any other way.
+type bufferUse struct {
+	method string
+	call   *ast.CallExpr
+	stack  []ast.Node
+}
+
+// growableBuffers finds the buffers body declares empty and records their
+// uses. Buffers referenced from closures are marked escaped.
+func growableBuffers(pass *analysis.Pass, body *ast.BlockStmt) []*growableBuffer {
+	buffers := make(map[*types.Var]*growableBuffer)
+	var order []*growableBuffer
+	declare := func(stmt ast.Stmt, stack []ast.Node, name *ast.Ident, value ast.Expr) {
+		v, ok := pass.TypesInfo.Defs[name].(*types.Var)
+		if !ok {
+			return
+		}
+		typ := emptyBufferInit(pass, v, value)
+		if typ == "" {
+			return
+		}
+		buf := &growableBuffer{v: v, typ: typ, decl: stmt}
+		switch parent := stack[len(stack)-1].(type) {
+		case *ast.BlockStmt:
+			buf.block = parent.List
+		case *ast.CaseClause:
+			buf.block = parent.Body
+		case *ast.CommClause:
+			buf.block = parent.Body
+		}
+		buffers[v] = buf
+		order = append(order, buf)
+	}
+
+	ast.PreorderStack(body, nil, func(n ast.Node, stack []ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			ast.Inspect(n, func(inner ast.Node) bool {
+				if ident, ok := inner.(*ast.Ident); ok {
+					v, _ := pass.TypesInfo.Uses[ident].(*types.Var)
+					if buf := buffers[v]; buf != nil {
+						buf.escaped = true
+					}
+				}
+				return true
+			})
+			return false
+		case *ast.DeclStmt:
+			gen, ok := n.Decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				return true
+			}
+			for _, spec := range gen.Specs {
+				valueSpec, ok := spec.(*ast.ValueSpec)
+				if !ok || (len(valueSpec.Values) != 0 && len(valueSpec.Values) != len(valueSpec.Names)) {
+					continue
+				}
+				for i, name := range valueSpec.Names {
+					var value ast.Expr
+					if len(valueSpec.Values) != 0 {
+						value = valueSpec.Values[i]
+					}
+					declare(n, stack, name, value)
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				if name, ok := lhs.(*ast.Ident); ok {
+					declare(n, stack, name, n.Rhs[i])
+				}
+			}
+		case *ast.Ident:
+			v, _ := pass.TypesInfo.Uses[n].(*types.Var)
+			buf := buffers[v]
+			if buf == nil {
+				return true
+			}
+			use := bufferUse{stack: append(slices.Clone(stack), n)}
+			if len(stack) >= 2 {
+				if sel, ok := stack[len(stack)-1].(*ast.SelectorExpr); ok && sel.X == n {
+					if call, ok := stack[len(stack)-2].(*ast.CallExpr); ok && call.Fun == sel {
+						use.method, use.call = sel.Sel.Name, call
+					}
+				}
+			}
+			buf.uses = append(buf.uses, use)
+		}
+		return true
+	})
+	return order
+}
+
+// emptyBufferInit returns the type name of v when it is a strings.Builder or
+// bytes.Buffer initialized empty by value: the zero value, T{}, &T{}, or
+// new(T).
+func emptyBufferInit(pass *analysis.Pass, v *types.Var, value ast.Expr) string {
+	typ := bufferTypeName(v.Type())
+	if typ == "" {
+		return ""
+	}
+	if value == nil {
+		if _, isPtr := v.Type().(*types.Pointer); isPtr {
+			return ""
+		}
+		return typ
+	}
+	switch e := ast.Unparen(value).(type) {
+	case *ast.CompositeLit:
+		if len(e.Elts) == 0 {
+			return typ
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := e.X.(*ast.CompositeLit); ok && e.Op == token.AND && len(lit.Elts) == 0 {
+			return typ
+		}
+	case *ast.CallExpr:
+		if ident, ok := ast.Unparen(e.Fun).(*ast.Ident); ok && ident.Name == "new" {
+			if _, builtin := pass.TypesInfo.Uses[ident].(*types.Builtin); builtin {
+				return typ
+			}
+		}
+	}
+	return ""
+}
+
+// bufferTypeName returns "strings.Builder" or "bytes.Buffer" when t is one of
+// them or a pointer to one, and "" otherwise.
+func bufferTypeName(t types.Type) string {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return ""
+	}
+	switch path, name := named.Obj().Pkg().Path(), named.Obj().Name(); {
+	case path == "strings" && name == "Builder", path == "bytes" && name == "Buffer":
+		return named.Obj().Pkg().Name() + "." + name
+	}
+	return ""
+}
+
+// checkBufferGrowth reports buf when it is written inside a loop of known
+// length, or by enough straight-line writes of known size, without Grow.
+func checkBufferGrowth(pass *analysis.Pass, rule ruleset.Rule, body *ast.BlockStmt, buf *growableBuffer) {
+	if buf.escaped {
+		return
+	}
+	loops := make(map[ast.Stmt][]bufferUse)
+	var loopOrder []ast.Stmt
+	var straight []bufferUse
+	for _, use := range buf.uses {
+		switch use.method {
+		case "Grow", "Reset", "Truncate":
+			// Sized or reused by hand.
+			return
+		}
+		loop := innermostLoop(use.stack)
+		if loop != nil && loop.Pos() <= buf.decl.Pos() {
+			loop = nil
+		}
+		if loop == nil {
+			straight = append(straight, use)
+			continue
+		}
+		if _, seen := loops[loop]; !seen {
+			loopOrder = append(loopOrder, loop)
+		}
+		loops[loop] = append(loops[loop], use)
+	}
+
+	if len(loopOrder) == 0 {
+		checkStraightGrowth(pass, rule, body, buf, straight)
+		return
+	}
+	for _, loop := range loopOrder {
+		checkLoopGrowth(pass, rule, body, buf, loop, loops[loop])
+	}
+}
+
+func checkLoopGrowth(
+	pass *analysis.Pass,
+	rule ruleset.Rule,
+	body *ast.BlockStmt,
+	buf *growableBuffer,
+	loop ast.Stmt,
+	uses []bufferUse,
+) {
+	first := slices.IndexFunc(uses, func(use bufferUse) bool { return isBufferWrite(use.method) })
+	if first < 0 {
+		return
+	}
+	count, known := loopCount(pass, loop)
+	if !known {
+		return
+	}
+
+	name := buf.v.Name()
+	size, sized := bufferGrowth(pass, &loopScope{pass: pass, loop: loop, fn: body}, uses)
+	if !sized || count == "" {
+		report(pass, uses[first].call.Pos(), rule, fmt.Sprintf(
+			"%s %s grows inside a loop of known length without Grow; call %s.Grow with the expected total size "+
+				"before the loop", buf.typ, name, name))
+		return
+	}
+	if size.zero() {
+		return
+	}
+
+	total := size.times(count)
+	detail := fmt.Sprintf("%s %s grows inside a loop of known length without Grow; call %s.Grow(%s) before the loop",
+		buf.typ, name, name, total)
+	if fix, ok := growFix(pass, loop, buf.v, total); ok {
+		reportWithFixes(pass, uses[first].call, rule, detail, fix)
+		return
+	}
+	report(pass, uses[first].call.Pos(), rule, detail)
+}
+
+// checkStraightGrowth reports buf when every write to it is a statement of
+// the block declaring it and their sizes add up to a known total.
+func checkStraightGrowth(
+	pass *analysis.Pass,
+	rule ruleset.Rule,
+	body *ast.BlockStmt,
+	buf *growableBuffer,
+	uses []bufferUse,
+) {
+	var writes []bufferUse
+	var stmts []ast.Stmt
+	for _, use := range uses {
+		if isBufferRead(use.method) {
+			continue
+		}
+		if !isBufferWrite(use.method) || len(use.stack) < 4 {
+			return
+		}
+		stmt, ok := use.stack[len(use.stack)-4].(*ast.ExprStmt)
+		if !ok || !slices.Contains(buf.block, ast.Stmt(stmt)) {
+			return
+		}
+		writes = append(writes, use)
+		stmts = append(stmts, stmt)
+	}
+	if len(writes) < builderGrowMinWrites {
+		return
+	}
+
+	// The span of the writes stands in for a loop: sizes must not change
+	// between the first write, where Grow goes, and the write they measure.
+	span := &ast.BlockStmt{Lbrace: stmts[0].Pos(), Rbrace: stmts[len(stmts)-1].End() - 1}
+	size, ok := bufferGrowth(pass, &loopScope{pass: pass, loop: span, fn: body}, writes)
+	// Append's first allocation already covers a few bytes.
+	if !ok || (len(size.terms) == 0 && size.n <= 8) {
+		return
+	}
+
+	name, total := buf.v.Name(), size.String()
+	detail := fmt.Sprintf("%s %s receives %d writes of known total size without Grow; call %s.Grow(%s) before the first",
+		buf.typ, name, len(writes), name, total)
+	if fix, ok := growFix(pass, stmts[0], buf.v, total); ok {
+		reportWithFixes(pass, writes[0].call, rule, detail, fix)
+		return
+	}
+	report(pass, writes[0].call.Pos(), rule, detail)
+}
+
+func isBufferWrite(method string) bool {
+	switch method {
+	case "WriteString", "Write", "WriteByte", "WriteRune":
+		return true
+	}
+	return false
+}
+
+func isBufferRead(method string) bool {
+	switch method {
+	case "String", "Len", "Cap", "Bytes":
+		return true
+	}
+	return false
+}
+
+// byteCount is a size expression: a constant number of bytes plus len terms.
+type byteCount struct {
+	n     int64
+	terms []string
+}
+
+func (c byteCount) zero() bool {
+	return c.n == 0 && len(c.terms) == 0
+}
+
+func (c byteCount) String() string {
+	parts := slices.Clone(c.terms)
+	if c.n > 0 || len(parts) == 0 {
+		parts = append(parts, strconv.FormatInt(c.n, 10))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// times multiplies the size by the iteration count expression count.
+func (c byteCount) times(count string) string {
+	if n, err := strconv.ParseInt(count, 10, 64); err == nil && len(c.terms) == 0 {
+		return strconv.FormatInt(n*c.n, 10)
+	}
+	per := c.String()
+	if per == "1" {
+		return count
+	}
+	return parenthesizeSum(count) + " * " + parenthesizeSum(per)
+}
+
+// parenthesizeSum wraps expr in parentheses when it is a binary expression,
+// so that it can be multiplied.
+func parenthesizeSum(expr string) string {
+	if e, err := parser.ParseExpr(expr); err == nil {
+		if _, ok := e.(*ast.BinaryExpr); !ok {
+			return expr
+		}
+	}
+	return "(" + expr + ")"
+}
+
+// bufferGrowth adds up the bytes that uses write. Writes count when their
+// size is a constant or the length of a string that scope proves invariant.
+func bufferGrowth(pass *analysis.Pass, scope *loopScope, uses []bufferUse) (byteCount, bool) {
+	var size byteCount
+	for _, use := range uses {
+		switch {
+		case isBufferRead(use.method):
+			continue
+		case !isBufferWrite(use.method) || len(use.call.Args) != 1:
+			return byteCount{}, false
+		}
+		arg := use.call.Args[0]
+		switch use.method {
+		case "WriteByte":
+			size.n++
+		case "WriteRune":
+			n := utf8.UTFMax
+			if r, ok := constInt(pass, arg); ok && utf8.RuneLen(rune(r)) > 0 {
+				n = utf8.RuneLen(rune(r))
+			}
+			size.n += int64(n)
+		default:
+			if n, ok := staticByteLen(pass, arg, 0); ok {
+				size.n += n
+				continue
+			}
+			if !isString(pass.TypesInfo, arg) || !scope.invariant(arg) {
+				return byteCount{}, false
+			}
+			size.terms = append(size.terms, "len("+types.ExprString(arg)+")")
+		}
+	}
+	return size, true
+}
+
+// loopCount reports whether loop runs a number of iterations known on entry
+// and returns an expression for it, or "" when it cannot be written before the
+// loop.
+func loopCount(pass *analysis.Pass, loop ast.Stmt) (string, bool) {
+	switch s := loop.(type) {
+	case *ast.RangeStmt:
+		if !hasKnownLength(pass.TypesInfo, s.X) {
+			return "", false
+		}
+		if n, ok := constArrayLen(pass.TypesInfo.TypeOf(s.X)); ok {
+			return strconv.FormatInt(n, 10), true
+		}
+		if n, ok := constInt(pass, s.X); ok {
+			return strconv.FormatInt(n, 10), true
+		}
+		if !isPlainRef(s.X) {
+			return "", true
+		}
+		if basic, ok := pass.TypesInfo.TypeOf(s.X).Underlying().(*types.Basic); ok && basic.Info()&types.IsInteger != 0 {
+			return clampedCount(pass, s.X, 0), true
+		}
+		return "len(" + types.ExprString(s.X) + ")", true
+	case *ast.ForStmt:
+		if !isCountedLoop(s) {
+			return "", false
+		}
+		init, _ := s.Init.(*ast.AssignStmt)
+		cond, _ := s.Cond.(*ast.BinaryExpr)
+		lo, ok := constInt(pass, init.Rhs[0])
+		if !ok {
+			return "", true
+		}
+		delta := -lo
+		if cond.Op == token.LEQ {
+			delta++
+		}
+		if hi, ok := constInt(pass, cond.Y); ok {
+			return strconv.FormatInt(max(hi+delta, 0), 10), true
+		}
+		return clampedCount(pass, cond.Y, delta), true
+	}
+	return "", false
+}
+
+// clampedCount returns the int expression bound + delta for a loop count,
+// wrapped in max(..., 0) unless it cannot be negative: Grow panics on a
+// negative size where the loop would just not run. It returns "" when the
+// clamp is needed but the package predates the max builtin of Go 1.21.
+func clampedCount(pass *analysis.Pass, bound ast.Expr, delta int64) string {
+	count := types.ExprString(bound)
+	nonNegative := extractCall(ast.Unparen(bound), "len") != nil
+	if basic, ok := pass.TypesInfo.TypeOf(bound).Underlying().(*types.Basic); ok && !nonNegative {
+		nonNegative = basic.Info()&types.IsUnsigned != 0
+		if basic.Kind() != types.Int && basic.Kind() != types.UntypedInt {
+			count = "int(" + count + ")"
+		}
+	}
+	switch {
+	case delta > 0:
+		count = fmt.Sprintf("%s + %d", count, delta)
+	case delta < 0:
+		count = fmt.Sprintf("%s - %d", count, -delta)
+		nonNegative = false
+	}
+	if nonNegative {
+		return count
+	}
+	if goVersion := pass.Pkg.GoVersion(); goVersion != "" && version.Compare(goVersion, "go1.21") < 0 {
+		return ""
+	}
+	return "max(" + count + ", 0)"
+}
+
+// isPlainRef reports whether expr is an identifier or a chain of field
+// selections on one, which can be evaluated again without side effects.
+func isPlainRef(expr ast.Expr) bool {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isPlainRef(e.X)
+	}
+	return false
+}
+
+// growFix inserts v.Grow(total) in front of stmt.
+func growFix(pass *analysis.Pass, stmt ast.Stmt, v *types.Var, total string) (analysis.SuggestedFix, bool) {
+	anchor := statementAnchor(enclosingPath(pass, stmt), stmt)
+	if !nameResolvesTo(pass, anchor.Pos(), v.Name(), v) {
+		return analysis.SuggestedFix{}, false
+	}
+	text := fmt.Sprintf("%s.Grow(%s)\n%s", v.Name(), total, lineIndent(pass, anchor.Pos()))
+	return analysis.SuggestedFix{
+		Message:   fmt.Sprintf("Grow %s by %s up front", v.Name(), total),
+		TextEdits: []analysis.TextEdit{{Pos: anchor.Pos(), End: anchor.Pos(), NewText: []byte(text)}},
+	}, true
+}
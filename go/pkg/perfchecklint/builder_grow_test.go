@@ -0,0 +1,189 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilderGrowSuggestsCapacityForLoops(t *testing.T) {
+	src := `package sample
+
+import (
+	"bytes"
+	"strings"
+)
+
+func render(ids []int, sep string, n int) (string, []byte) {
+	var b strings.Builder
+	for range ids {
+		b.WriteString(sep)
+		b.WriteString("id")
+		b.WriteByte(';')
+	}
+	buf := new(bytes.Buffer)
+	for i := 1; i <= n; i++ {
+		buf.WriteRune('é')
+	}
+	return b.String(), buf.Bytes()
+}
+`
+	want := `package sample
+
+import (
+	"bytes"
+	"strings"
+)
+
+func render(ids []int, sep string, n int) (string, []byte) {
+	var b strings.Builder
+	b.Grow(len(ids) * (len(sep) + 3))
+	for range ids {
+		b.WriteString(sep)
+		b.WriteString("id")
+		b.WriteByte(';')
+	}
+	buf := new(bytes.Buffer)
+	for i := 1; i <= n; i++ {
+		buf.WriteRune('é')
+	}
+	return b.String(), buf.Bytes()
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, builderGrowAnalyzer, "render.go", src)
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0].Message, "[perf_builder_grow] strings.Builder b grows inside a loop of known "+
+		"length without Grow; call b.Grow(len(ids) * (len(sep) + 3)) before the loop")
+	require.Contains(t, diags[1].Message, "bytes.Buffer buf grows inside a loop of known length without Grow; "+
+		"call buf.Grow(max(n, 0) * 2) before the loop")
+	require.Equal(t, want, applySuggestedFix(t, fset, src, diags[0]))
+}
+
+func TestBuilderGrowClampsCountsThatCanBeNegative(t *testing.T) {
+	src := `package sample
+
+import "strings"
+
+func join(xs []string, n int32) string {
+	var head strings.Builder
+	for i := 1; i < len(xs); i++ {
+		head.WriteByte(',')
+	}
+	var pad strings.Builder
+	for range n {
+		pad.WriteString("ab")
+	}
+	var all strings.Builder
+	for i := 0; i < len(xs); i++ {
+		all.WriteByte(',')
+	}
+	return head.String() + pad.String() + all.String()
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, builderGrowAnalyzer, "join.go", src)
+	require.Len(t, diags, 3)
+	require.Contains(t, diags[0].Message, "call head.Grow(max(len(xs) - 1, 0)) before the loop")
+	require.Contains(t, diags[1].Message, "call pad.Grow(max(int(n), 0) * 2) before the loop")
+	require.Contains(t, diags[2].Message, "call all.Grow(len(xs)) before the loop")
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[1]),
+		"\tvar pad strings.Builder\n\tpad.Grow(max(int(n), 0) * 2)\n\tfor range n {")
+}
+
+func TestBuilderGrowReportsVaryingWritesWithoutFix(t *testing.T) {
+	src := `package sample
+
+import "strings"
+
+func join(lines map[string]bool) string {
+	b := strings.Builder{}
+	for line := range lines {
+		b.WriteString(line)
+	}
+	return b.String()
+}
+`
+
+	diags := runAnalyzerOnSource(t, builderGrowAnalyzer, "join.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "call b.Grow with the expected total size before the loop")
+	require.Empty(t, diags[0].SuggestedFixes)
+}
+
+func TestBuilderGrowReportsStraightLineWrites(t *testing.T) {
+	src := `package sample
+
+import "strings"
+
+func header(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+	b.WriteByte(' ')
+	b.WriteString(path)
+	b.WriteString(" HTTP/1.1\r\n")
+	return b.String()
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, builderGrowAnalyzer, "header.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "strings.Builder b receives 4 writes of known total size without Grow; "+
+		"call b.Grow(len(method) + len(path) + 12) before the first")
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[0]),
+		"\tvar b strings.Builder\n\tb.Grow(len(method) + len(path) + 12)\n\tb.WriteString(method)\n")
+}
+
+func TestBuilderGrowIgnoresSizedAndUnboundedBuilders(t *testing.T) {
+	src := `package sample
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+func build(items []string, ch <-chan string, dst *strings.Builder) string {
+	var grown strings.Builder
+	grown.Grow(64)
+	for _, item := range items {
+		grown.WriteString(item)
+	}
+	var streamed strings.Builder
+	for item := range ch {
+		streamed.WriteString(item)
+	}
+	for _, item := range items {
+		dst.WriteString(item)
+	}
+	seeded := bytes.NewBufferString("x")
+	for _, item := range items {
+		seeded.WriteString(item)
+	}
+	var reused strings.Builder
+	for _, item := range items {
+		reused.Reset()
+		reused.WriteString(item)
+	}
+	var shared strings.Builder
+	write := func(s string) { shared.WriteString(s) }
+	for _, item := range items {
+		write(item)
+		shared.WriteString(item)
+	}
+	var short strings.Builder
+	short.WriteString("a")
+	short.WriteString("b")
+	short.WriteString("c")
+	var formatted strings.Builder
+	fmt.Fprint(&formatted, "x")
+	formatted.WriteString("a")
+	formatted.WriteString("b")
+	formatted.WriteString("c")
+	return grown.String() + streamed.String() + short.String() + formatted.String()
+}
+`
+
+	diags := runAnalyzerOnSource(t, builderGrowAnalyzer, "build.go", src)
+	require.Empty(t, diags)
+}
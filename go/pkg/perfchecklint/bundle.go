@@ -14,12 +14,25 @@ type Bundle struct {
 //
 // Name defaults to "perfcheck" and Description defaults to
 // "Performance-by-default analyzers" when unset.
+//
+// Config takes precedence over ConfigFile; when both are unset every package
+// uses the nearest .perfcheck.yaml found by walking up from its directory.
+// The selection also trims Analyzers so disabled rules do not run at all.
+// ProfileFile overrides the pprof profile named by the configuration.
 type BuildOptions struct {
 	Name        string
 	Description string
+	ConfigFile  string
+	Config      *Config
+	ProfileFile string
 }
 
 // Build returns the perfcheck analyzer bundle with normalized metadata.
+//
+// Each bundle gets its own copies of the analyzers, bound to opts, so
+// bundles built with different options can run side by side; a
+// configuration file that fails to load is reported by the analyzers when
+// they run.
 func Build(opts BuildOptions) Bundle {
 	name := opts.Name
 	if name == "" {
@@ -29,10 +42,14 @@ func Build(opts BuildOptions) Bundle {
 	if desc == "" {
 		desc = "Performance-by-default analyzers"
 	}
+	cfg := opts.Config
+	if cfg == nil && opts.ConfigFile != "" {
+		cfg, _ = cachedConfig(opts.ConfigFile)
+	}
 	return Bundle{
 		Name:        name,
 		Description: desc,
-		Analyzers:   Analyzers(),
+		Analyzers:   bundleAnalyzers(opts, cfg),
 	}
 }
 
@@ -0,0 +1,327 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// costKind enumerates the expensive operations tracked across function calls.
+type costKind int
+
+const (
+	costRegexp costKind = iota
+	costReflect
+	costGoroutine
+	costAlloc
+	numCostKinds
+)
+
+// maxCostChain bounds how many calls deep expensive work is traced, keeping
+// facts small and messages readable.
+const maxCostChain = 6
+
+var costDescriptions = [numCostKinds]string{
+	costRegexp:    "compiles a regexp",
+	costReflect:   "uses reflection",
+	costGoroutine: "spawns a goroutine",
+	costAlloc:     "grows a collection without preallocating",
+}
+
+// costFact is exported for functions that perform expensive work directly or
+// through the functions they call. Each chain lists the calls from the
+// function down to the operation, such as ["pkg.compile", "regexp.MustCompile"];
+// a nil chain means the function does no such work.
+type costFact struct {
+	Chains [numCostKinds][]string
+}
+
+func (*costFact) AFact() {}
+
+func (f *costFact) empty() bool {
+	for _, chain := range f.Chains {
+		if chain != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *costFact) String() string {
+	var parts []string
+	for kind, chain := range f.Chains {
+		if chain != nil {
+			parts = append(parts, costDescriptions[kind]+" via "+strings.Join(chain, " -> "))
+		}
+	}
+	return "costs(" + strings.Join(parts, "; ") + ")"
+}
+
+// callCosts is the result of callCostAnalyzer: the facts of the package's own
+// functions and of every function it calls from other packages.
+type callCosts struct {
+	facts map[*types.Func]*costFact
+}
+
+func (c *callCosts) chain(fn *types.Func, kind costKind) []string {
+	if c == nil {
+		return nil
+	}
+	if fact := c.facts[fn]; fact != nil {
+		return fact.Chains[kind]
+	}
+	return nil
+}
+
+// callCostAnalyzer summarizes which functions compile regexps, use
+// reflection, spawn goroutines, or grow unsized collections inside loops, and
+// exports the summaries as facts so callers in other packages see through
+// helpers. Collection growth is the unsized append or map insert that
+// perf_preallocate_collections itself reports, not every allocation.
+// Standard library packages are not summarized: nearly all of them allocate
+// or reflect somewhere, which would flag every fmt or strings call.
+var callCostAnalyzer = &analysis.Analyzer{
+	Name:       "perfcheck_call_costs",
+	Doc:        "summarizes expensive work performed by functions for interprocedural loop checks",
+	FactTypes:  []analysis.Fact{(*costFact)(nil)},
+	ResultType: reflect.TypeOf((*callCosts)(nil)),
+	Run: func(pass *analysis.Pass) (any, error) {
+		costs := &callCosts{facts: make(map[*types.Func]*costFact)}
+		if isStdlibPass(pass) {
+			return costs, nil
+		}
+
+		type summary struct {
+			fn      *types.Func
+			fact    *costFact
+			callees []*types.Func
+		}
+		var summaries []*summary
+		for _, file := range pass.Files {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				scan := &costScan{info: pass.TypesInfo, fact: &costFact{}, seen: make(map[*types.Func]bool)}
+				scan.walk(fd.Body)
+				scanPreallocation(pass, fd.Body, func(_ token.Pos, message string) {
+					scan.set(costAlloc, message)
+				})
+				summaries = append(summaries, &summary{fn: fn, fact: scan.fact, callees: scan.callees})
+				costs.facts[fn] = scan.fact
+			}
+		}
+
+		for _, s := range summaries {
+			for _, callee := range s.callees {
+				if _, ok := costs.facts[callee]; ok || callee.Pkg() == pass.Pkg {
+					continue
+				}
+				fact := new(costFact)
+				if pass.ImportObjectFact(callee, fact) {
+					costs.facts[callee] = fact
+				}
+			}
+		}
+
+		// Propagate costs through local calls until nothing changes; the
+		// first chain found for a kind is kept, so recursion terminates.
+		for changed := true; changed; {
+			changed = false
+			for _, s := range summaries {
+				for _, callee := range s.callees {
+					fact := costs.facts[callee]
+					if fact == nil {
+						continue
+					}
+					for kind, chain := range fact.Chains {
+						if s.fact.Chains[kind] != nil || chain == nil || len(chain) >= maxCostChain {
+							continue
+						}
+						s.fact.Chains[kind] = append([]string{funcDisplayName(callee)}, chain...)
+						changed = true
+					}
+				}
+			}
+		}
+
+		for _, s := range summaries {
+			if !s.fact.empty() {
+				pass.ExportObjectFact(s.fn, s.fact)
+			}
+		}
+		return costs, nil
+	},
+}
+
+// costScan collects the direct costs and static callees of a function body.
+type costScan struct {
+	info    *types.Info
+	fact    *costFact
+	callees []*types.Func
+	seen    map[*types.Func]bool
+}
+
+func (s *costScan) set(kind costKind, leaf string) {
+	if s.fact.Chains[kind] == nil {
+		s.fact.Chains[kind] = []string{leaf}
+	}
+}
+
+func (s *costScan) walk(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			s.set(costGoroutine, "go statement")
+		case *ast.CallExpr:
+			s.call(n)
+		}
+		return true
+	})
+}
+
+func (s *costScan) call(call *ast.CallExpr) {
+	fn := typeutil.StaticCallee(s.info, call)
+	if fn == nil || fn.Pkg() == nil {
+		return
+	}
+	fn = fn.Origin()
+	switch {
+	case isRegexpCompile(fn):
+		s.set(costRegexp, funcDisplayName(fn))
+	case fn.Pkg().Path() == "reflect":
+		s.set(costReflect, funcDisplayName(fn))
+	case !s.seen[fn]:
+		s.seen[fn] = true
+		s.callees = append(s.callees, fn)
+	}
+}
+
+func isSliceOrMap(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch t.Underlying().(type) {
+	case *types.Slice, *types.Map:
+		return true
+	}
+	return false
+}
+
+// isRegexpCompile reports whether fn is one of the regexp compile functions.
+func isRegexpCompile(fn *types.Func) bool {
+	if fn.Pkg() == nil || fn.Pkg().Path() != "regexp" {
+		return false
+	}
+	switch fn.Name() {
+	case "Compile", "MustCompile", "CompilePOSIX", "MustCompilePOSIX":
+		return true
+	}
+	return false
+}
+
+// funcDisplayName names fn as "pkg.F", "pkg.T.M", or "pkg.(*T).M".
+func funcDisplayName(fn *types.Func) string {
+	if fn == nil {
+		return "?"
+	}
+	prefix := ""
+	if fn.Pkg() != nil {
+		prefix = fn.Pkg().Name() + "."
+	}
+	recv := fn.Signature().Recv()
+	if recv == nil {
+		return prefix + fn.Name()
+	}
+	t := recv.Type()
+	pointer := false
+	if ptr, ok := t.(*types.Pointer); ok {
+		t, pointer = ptr.Elem(), true
+	}
+	name := types.TypeString(t, func(*types.Package) string { return "" })
+	if named, ok := types.Unalias(t).(*types.Named); ok {
+		name = named.Obj().Name()
+	}
+	if pointer {
+		return prefix + "(*" + name + ")." + fn.Name()
+	}
+	return prefix + name + "." + fn.Name()
+}
+
+// isStdlibPass reports whether pass analyzes a package under GOROOT.
+func isStdlibPass(pass *analysis.Pass) bool {
+	dir := packageDir(pass)
+	if dir == "" || build.Default.GOROOT == "" {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Join(build.Default.GOROOT, "src"), dir)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// reportCostlyCalls reports calls made inside loops to functions whose facts
+// record work of the given kind, naming the call chain that reaches it.
+func reportCostlyCalls(pass *analysis.Pass, ins *inspector.Inspector, kind costKind, rule ruleset.Rule) {
+	costs, _ := pass.ResultOf[callCostAnalyzer].(*callCosts)
+	ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+		if !push || !insideLoopBody(stack) {
+			return true
+		}
+		call, _ := node.(*ast.CallExpr)
+		fn := typeutil.StaticCallee(pass.TypesInfo, call)
+		if fn == nil {
+			return true
+		}
+		chain := costs.chain(fn.Origin(), kind)
+		if chain == nil {
+			return true
+		}
+		name := funcDisplayName(fn)
+		report(pass, call.Pos(), rule, fmt.Sprintf("call to %s %s on every iteration via %s",
+			name, costDescriptions[kind], strings.Join(append([]string{name}, chain...), " -> ")))
+		return true
+	})
+}
+
+// insideLoopBody reports whether the innermost node of stack executes once per
+// iteration of an enclosing loop in the same function.
+func insideLoopBody(stack []ast.Node) bool {
+	return innermostLoop(stack) != nil
+}
+
+// innermostLoop returns the closest loop in the same function whose body,
+// condition, or post statement holds the innermost node of stack, or nil.
+func innermostLoop(stack []ast.Node) ast.Stmt {
+	for i := len(stack) - 2; i >= 0; i-- {
+		switch parent := stack[i].(type) {
+		case *ast.FuncLit, *ast.FuncDecl:
+			return nil
+		case *ast.ForStmt:
+			child := stack[i+1]
+			if child == parent.Body || (parent.Cond != nil && child == parent.Cond) ||
+				(parent.Post != nil && child == parent.Post) {
+				return parent
+			}
+		case *ast.RangeStmt:
+			if stack[i+1] == parent.Body {
+				return parent
+			}
+		}
+	}
+	return nil
+}
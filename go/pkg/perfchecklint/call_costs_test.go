@@ -0,0 +1,188 @@
+package perfchecklint
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+)
+
+const costHelpersSource = `package helpers
+
+import (
+	"reflect"
+	"regexp"
+)
+
+func Match(pattern, s string) bool { return compile(pattern).MatchString(s) }
+
+func compile(pattern string) *regexp.Regexp { return regexp.MustCompile(pattern) }
+
+func Kind(v any) string { return reflect.TypeOf(v).String() }
+
+func Spawn(f func()) { go f() }
+
+func Collect(n int) []int {
+	var out []int
+	for i := 0; i < n; i++ {
+		out = append(out, i)
+	}
+	return out
+}
+
+func Sized(n int) []*[]int {
+	out := make([]*[]int, 0, n)
+	for i := 0; i < n; i++ {
+		row := make([]int, 0, 4)
+		row = append(row, i)
+		out = append(out, &row)
+		_ = &[]int{i}
+	}
+	return out
+}
+
+func Cheap(a, b int) int { return a + b }
+
+func Loop(n int) {
+	if n > 0 {
+		Loop(n - 1)
+	}
+}
+`
+
+const costAppSource = `package app
+
+import "example.com/helpers"
+
+type matcher struct{}
+
+func (matcher) check(s string) bool { return helpers.Match("a+", s) }
+
+func Run(items []string) {
+	m := matcher{}
+	for _, item := range items {
+		m.check(item)
+		helpers.Kind(item)
+		helpers.Spawn(func() {})
+		helpers.Collect(3)
+		helpers.Sized(3)
+		helpers.Cheap(1, 2)
+		helpers.Loop(3)
+		func() { m.check(item) }()
+	}
+	m.check("outside")
+}
+`
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// runOnDependentPackages type-checks a helpers package and an app package
+// importing it, runs analyzer on both so facts flow across the import, and
+// returns the diagnostics reported for the app package.
+func runOnDependentPackages(t *testing.T, analyzer *analysis.Analyzer) []analysis.Diagnostic {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	check := func(path, filename, src string, imp types.Importer) ([]*ast.File, *types.Info, *types.Package) {
+		file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		require.NoError(t, err)
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Instances:  make(map[*ast.Ident]types.Instance),
+		}
+		pkg, err := (&types.Config{Importer: imp}).Check(path, fset, []*ast.File{file}, info)
+		require.NoError(t, err)
+		return []*ast.File{file}, info, pkg
+	}
+
+	files, info, helpers := check("example.com/helpers", "helpers.go", costHelpersSource, importer.Default())
+	runAnalyzerGraph(t, analyzer, fset, files, info, helpers, nil)
+
+	imp := importerFunc(func(path string) (*types.Package, error) {
+		if path == helpers.Path() {
+			return helpers, nil
+		}
+		return importer.Default().Import(path)
+	})
+	files, info, app := check("example.com/app", "app.go", costAppSource, imp)
+	diags, _ := runAnalyzerGraph(t, analyzer, fset, files, info, app, nil)
+	return diags
+}
+
+func TestCallCostsReportExpensiveHelpersAcrossPackages(t *testing.T) {
+	cases := map[*analysis.Analyzer]string{
+		regexCompileLoopAnalyzer: "call to app.matcher.check compiles a regexp on every iteration via " +
+			"app.matcher.check -> helpers.Match -> helpers.compile -> regexp.MustCompile",
+		reflectionLoopAnalyzer: "call to helpers.Kind uses reflection on every iteration via " +
+			"helpers.Kind -> reflect.TypeOf",
+		boundConcurrencyAnalyzer: "call to helpers.Spawn spawns a goroutine on every iteration via " +
+			"helpers.Spawn -> go statement",
+		preallocateCollectionsAnalyzer: "call to helpers.Collect grows a collection without preallocating on every " +
+			"iteration via helpers.Collect -> append inside loop without preallocated capacity",
+	}
+	for analyzer, want := range cases {
+		var matched []string
+		for _, diag := range runOnDependentPackages(t, analyzer) {
+			if strings.Contains(diag.Message, "call to ") {
+				matched = append(matched, diag.Message)
+			}
+		}
+		require.Len(t, matched, 1, analyzer.Name)
+		require.Contains(t, matched[0], want, analyzer.Name)
+	}
+}
+
+func TestCallCostFactsPropagateWithinPackage(t *testing.T) {
+	src := `package sample
+
+import "regexp"
+
+type set[T any] struct{ items []T }
+
+func (s *set[T]) Add(v T) { s.items = append(s.items, v); _ = regexp.MustCompile("x") }
+
+func a() { b() }
+func b() { a(); new(set[int]).Add(1) }
+`
+	_, result := runCallCosts(t, src)
+	costs, ok := result.(*callCosts)
+	require.True(t, ok)
+
+	chains := make(map[string][]string)
+	for fn, fact := range costs.facts {
+		chains[fn.Name()] = fact.Chains[costRegexp]
+	}
+	require.Equal(t, []string{"regexp.MustCompile"}, chains["Add"])
+	require.Equal(t, []string{"sample.(*set).Add", "regexp.MustCompile"}, chains["b"])
+	require.Equal(t, []string{"sample.b", "sample.(*set).Add", "regexp.MustCompile"}, chains["a"])
+}
+
+func runCallCosts(t *testing.T, src string) ([]analysis.Diagnostic, any) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "sample.go", src, 0)
+	require.NoError(t, err)
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+	}
+	pkg, err := (&types.Config{Importer: importer.Default()}).Check("sample", fset, []*ast.File{file}, info)
+	require.NoError(t, err)
+	return runAnalyzerGraph(t, callCostAnalyzer, fset, []*ast.File{file}, info, pkg, nil)
+}
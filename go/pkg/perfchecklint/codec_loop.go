@@ -0,0 +1,94 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// codecCall is a reflection-driven encoding function and the advice for
+// calling it from a loop.
+type codecCall struct {
+	pkg  string
+	name string
+	hint string
+}
+
+var codecCalls = []codecCall{
+	{"encoding/json", "Marshal", "encode through one json.Encoder reused across the stream, or hoist the call"},
+	{"encoding/json", "MarshalIndent", "encode through one json.Encoder with SetIndent, or hoist the call"},
+	{"encoding/json", "Unmarshal", "decode the stream with one json.Decoder, or hoist the call"},
+	{"encoding/binary", "Read", "decode fixed layouts with binary.BigEndian.Uint* or binary.LittleEndian.Uint*"},
+	{"encoding/binary", "Write", "encode fixed layouts with binary.BigEndian.PutUint* or binary.LittleEndian.PutUint*"},
+	{"encoding/binary", "Size", "compute the size once outside the loop"},
+	{"encoding/gob", "NewEncoder", "create one gob.Encoder before the loop so type information is sent once"},
+	{"encoding/gob", "NewDecoder", "create one gob.Decoder before the loop so type information is read once"},
+}
+
+var codecLoopAnalyzer = &analysis.Analyzer{
+	Name:     "perf_avoid_codec_reflection_loop",
+	Doc:      "reports reflection-driven json, gob, and binary encoding calls inside loops",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_avoid_codec_reflection_loop")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_avoid_codec_reflection_loop not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		codecs := importedCodecs(pass.Pkg)
+		if len(codecs) == 0 {
+			return nil, nil
+		}
+
+		ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+			if !push || innermostLoop(stack) == nil {
+				return true
+			}
+			call, _ := node.(*ast.CallExpr)
+			fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+			if !ok {
+				return true
+			}
+			codec, ok := codecs[fn]
+			if !ok {
+				return true
+			}
+			report(pass, call.Pos(), rule, fmt.Sprintf(
+				"%s.%s reflects over its argument on every iteration; %s",
+				fn.Pkg().Name(), fn.Name(), codec.hint))
+			return true
+		})
+
+		return nil, nil
+	},
+}
+
+// importedCodecs resolves codecCalls against the packages pkg imports, so
+// calls are matched by the identity of the function object rather than by a
+// name that a local package or variable could shadow.
+func importedCodecs(pkg *types.Package) map[*types.Func]codecCall {
+	codecs := make(map[*types.Func]codecCall)
+	for _, imp := range pkg.Imports() {
+		for _, codec := range codecCalls {
+			if imp.Path() != codec.pkg {
+				continue
+			}
+			if fn, ok := imp.Scope().Lookup(codec.name).(*types.Func); ok {
+				codecs[fn] = codec
+			}
+		}
+	}
+	return codecs
+}
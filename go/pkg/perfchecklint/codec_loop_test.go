@@ -0,0 +1,98 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecLoopReportsReflectiveCodecsInLoops(t *testing.T) {
+	src := `package sample
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+type header struct {
+	Magic   uint32
+	Version uint16
+}
+
+func roundTrip(w io.Writer, r io.Reader, items []header, blobs [][]byte) error {
+	for _, item := range items {
+		if err := binary.Write(w, binary.BigEndian, item); err != nil {
+			return err
+		}
+		var h header
+		if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(item); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < len(blobs); i++ {
+		var h header
+		if err := json.Unmarshal(blobs[i], &h); err != nil {
+			return err
+		}
+		out, _ := json.MarshalIndent(h, "", "  ")
+		_ = out
+	}
+	return nil
+}
+`
+
+	diags := runAnalyzerOnSource(t, codecLoopAnalyzer, "codec.go", src)
+	require.Len(t, diags, 5)
+	require.Contains(t, diags[0].Message, "[perf_avoid_codec_reflection_loop] binary.Write reflects over its argument "+
+		"on every iteration; encode fixed layouts with binary.BigEndian.PutUint* or binary.LittleEndian.PutUint*")
+	require.Contains(t, diags[1].Message, "binary.Read reflects over its argument")
+	require.Contains(t, diags[2].Message, "gob.NewEncoder reflects over its argument on every iteration; "+
+		"create one gob.Encoder before the loop so type information is sent once")
+	require.Contains(t, diags[3].Message, "json.Unmarshal reflects over its argument on every iteration; "+
+		"decode the stream with one json.Decoder")
+	require.Contains(t, diags[4].Message, "json.MarshalIndent reflects over its argument")
+}
+
+func TestCodecLoopMatchesCalleesByIdentity(t *testing.T) {
+	src := `package sample
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) { return nil, nil }
+
+func stream(w io.Writer, items []int, json codec) error {
+	enc := gob.NewEncoder(w)
+	for _, item := range items {
+		if _, err := json.Marshal(item); err != nil {
+			return err
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+		go func() { _ = gob.NewEncoder(w) }()
+	}
+	return nil
+}
+
+func once(v any) ([]byte, error) {
+	_ = json.Marshal
+	return nil, nil
+}
+`
+
+	diags := runAnalyzerOnSource(t, codecLoopAnalyzer, "stream.go", src)
+	require.Empty(t, diags)
+}
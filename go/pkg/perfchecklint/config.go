@@ -0,0 +1,366 @@
+package perfchecklint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"gopkg.in/yaml.v3"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// ConfigFileNames lists the project configuration files perfcheck looks for,
+// in order of preference, when walking up from a package directory.
+var ConfigFileNames = []string{".perfcheck.yaml", ".perfcheck.yml"}
+
+// ConfigEnv names the environment variable that pins the configuration file
+// used by the unitchecker binary instead of discovering one.
+const ConfigEnv = "PERFCHECK_CONFIG"
+
+// SeverityOff disables a rule when used as a severity override.
+const SeverityOff = "off"
+
+var validSeverities = []string{"info", "warning", "medium", "high", "error", SeverityOff}
+
+// Config is the parsed form of a .perfcheck.yaml project configuration.
+//
+// Rules and Categories select what runs: when either include list is set,
+// only matching rules run; exclude lists always win. Severities overrides the
+// registry severity per rule ID ("off" disables the rule). Exclude holds
+// slash-separated path globs, relative to the configuration file, whose
+// diagnostics are dropped; "**" matches any number of directories and
+// patterns without a slash match file names in any directory. Profile
+// optionally weighs diagnostics by a pprof profile.
+type Config struct {
+	Rules      Selection         `yaml:"rules"`
+	Categories Selection         `yaml:"categories"`
+	Severities map[string]string `yaml:"severity"`
+	Exclude    []string          `yaml:"exclude"`
+	Profile    ProfileConfig     `yaml:"profile"`
+
+	// Dir anchors the Exclude globs; LoadConfig sets it to the file's directory.
+	Dir string `yaml:"-"`
+}
+
+// Selection lists identifiers to include or exclude.
+type Selection struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// LoadConfig reads and validates a perfcheck configuration file.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("perfcheck config: %w", err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("perfcheck config %s: %w", filename, err)
+	}
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, fmt.Errorf("perfcheck config: %w", err)
+	}
+	cfg.Dir = filepath.Dir(abs)
+	return cfg, nil
+}
+
+// ParseConfig decodes and validates configuration YAML. Unknown keys, rule
+// IDs, categories, and severities are rejected so typos fail loudly.
+func ParseConfig(data []byte) (*Config, error) {
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// FindConfig walks up from dir and returns the first configuration file
+// found, or an empty string when none exists.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range ConfigFileNames {
+			candidate := filepath.Join(dir, name)
+			info, err := os.Stat(candidate)
+			if err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Validate checks every referenced rule, category, severity, and glob.
+func (c *Config) Validate() error {
+	reg, err := ruleset.Default()
+	if err != nil {
+		return err
+	}
+	categories := make(map[string]bool)
+	for _, rule := range reg.All() {
+		categories[rule.Category] = true
+	}
+
+	var errs []error
+	for _, id := range slices.Concat(c.Rules.Include, c.Rules.Exclude) {
+		if _, ok := reg.RuleByID(id); !ok {
+			errs = append(errs, fmt.Errorf("unknown rule %q", id))
+		}
+	}
+	for _, category := range slices.Concat(c.Categories.Include, c.Categories.Exclude) {
+		if !categories[strings.ToLower(category)] {
+			errs = append(errs, fmt.Errorf("unknown category %q", category))
+		}
+	}
+	for id, severity := range c.Severities {
+		if _, ok := reg.RuleByID(id); !ok {
+			errs = append(errs, fmt.Errorf("severity override for unknown rule %q", id))
+		}
+		if !slices.Contains(validSeverities, strings.ToLower(severity)) {
+			errs = append(errs, fmt.Errorf("rule %s: invalid severity %q (want one of %s)",
+				id, severity, strings.Join(validSeverities, ", ")))
+		}
+	}
+	for _, pattern := range c.Exclude {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err))
+		}
+	}
+	if err := c.Profile.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Enabled reports whether the rule should report diagnostics. A nil Config
+// enables every rule.
+func (c *Config) Enabled(ruleID string) bool {
+	if c == nil {
+		return true
+	}
+	rule, ok := ruleset.MustDefault().RuleByID(ruleID)
+	if !ok {
+		return false
+	}
+	if len(c.Rules.Include) > 0 || len(c.Categories.Include) > 0 {
+		if !slices.Contains(c.Rules.Include, rule.ID) && !containsFold(c.Categories.Include, rule.Category) {
+			return false
+		}
+	}
+	if slices.Contains(c.Rules.Exclude, rule.ID) || containsFold(c.Categories.Exclude, rule.Category) {
+		return false
+	}
+	return c.Severity(rule.ID) != SeverityOff
+}
+
+// Severity returns the effective severity for the rule: the configured
+// override when present, otherwise the registry default.
+func (c *Config) Severity(ruleID string) string {
+	if c != nil {
+		if severity, ok := c.Severities[ruleID]; ok {
+			return strings.ToLower(severity)
+		}
+	}
+	rule, _ := ruleset.MustDefault().RuleByID(ruleID)
+	return rule.Severity
+}
+
+// Excluded reports whether diagnostics in filename are dropped by the
+// configured path globs.
+func (c *Config) Excluded(filename string) bool {
+	if c == nil || len(c.Exclude) == 0 || filename == "" {
+		return false
+	}
+	rel := filename
+	if c.Dir != "" {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return false
+		}
+		if rel, err = filepath.Rel(c.Dir, abs); err != nil || strings.HasPrefix(rel, "..") {
+			return false
+		}
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range c.Exclude {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProfilePath returns the pprof profile that weighs diagnostics: the
+// PERFCHECK_PROFILE environment variable when set, otherwise the configured
+// path resolved against the configuration directory.
+func (c *Config) ProfilePath() string {
+	if env := os.Getenv(ProfileEnv); env != "" {
+		return env
+	}
+	if c == nil || c.Profile.Path == "" {
+		return ""
+	}
+	if c.Dir != "" && !filepath.IsAbs(c.Profile.Path) {
+		return filepath.Join(c.Dir, c.Profile.Path)
+	}
+	return c.Profile.Path
+}
+
+func containsFold(values []string, want string) bool {
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, want) })
+}
+
+// matchGlob matches a slash-separated path against pattern, where "**"
+// matches zero or more directories and slash-free patterns match base names.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+var configCache sync.Map // absolute path -> *configEntry
+
+type configEntry struct {
+	once sync.Once
+	cfg  *Config
+	err  error
+}
+
+func cachedConfig(filename string) (*Config, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := configCache.LoadOrStore(abs, &configEntry{})
+	entry, _ := value.(*configEntry)
+	entry.once.Do(func() {
+		entry.cfg, entry.err = LoadConfig(abs)
+	})
+	return entry.cfg, entry.err
+}
+
+var configFile string
+
+// configAnalyzer resolves the configuration that applies to a package so the
+// rule analyzers and the directive audit filter diagnostics consistently.
+var configAnalyzer = &analysis.Analyzer{
+	Name:       "perfcheck_config",
+	Doc:        "loads the .perfcheck.yaml configuration that applies to a package",
+	ResultType: reflect.TypeOf((*Config)(nil)),
+	Run: func(pass *analysis.Pass) (any, error) {
+		return resolveConfig(pass, nil, configFile)
+	},
+}
+
+// newConfigAnalyzer returns a configuration analyzer bound to cfg, or to
+// filename when cfg is nil. It stands in for configAnalyzer in bundles built
+// by Build and so shares its name.
+func newConfigAnalyzer(cfg *Config, filename string) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:       configAnalyzer.Name,
+		Doc:        configAnalyzer.Doc,
+		ResultType: configAnalyzer.ResultType,
+		Run: func(pass *analysis.Pass) (any, error) {
+			return resolveConfig(pass, cfg, filename)
+		},
+	}
+}
+
+// resolveConfig returns cfg when set and otherwise the configuration that
+// applies to the package pass analyzes.
+func resolveConfig(pass *analysis.Pass, cfg *Config, filename string) (*Config, error) {
+	if cfg != nil {
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("perfcheck config: %w", err)
+		}
+		return cfg, nil
+	}
+	return ResolveConfig(packageDir(pass), filename)
+}
+
+// ResolveConfig returns the configuration that applies to files in dir: the
+// file named by filename or PERFCHECK_CONFIG, and otherwise the nearest
+// configuration file above dir. It returns nil when there is none.
+func ResolveConfig(dir, filename string) (*Config, error) {
+	if filename == "" {
+		filename = os.Getenv(ConfigEnv)
+	}
+	if filename == "" {
+		if dir == "" {
+			return nil, nil
+		}
+		found, err := FindConfig(dir)
+		if err != nil {
+			return nil, fmt.Errorf("perfcheck config: %w", err)
+		}
+		if found == "" {
+			return nil, nil
+		}
+		filename = found
+	}
+	return cachedConfig(filename)
+}
+
+func init() {
+	configAnalyzer.Flags.StringVar(&configFile, "config", "",
+		"path to a .perfcheck.yaml file (default: discovered from the package directory upwards)")
+}
+
+func packageDir(pass *analysis.Pass) string {
+	for _, file := range pass.Files {
+		if tf := pass.Fset.File(file.Pos()); tf != nil && filepath.IsAbs(tf.Name()) {
+			return filepath.Dir(tf.Name())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,243 @@
+package perfchecklint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+)
+
+const deferLoopSource = `package sample
+
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		defer f.Close()
+	}
+}
+`
+
+func TestParseConfigRejectsUnknownEntries(t *testing.T) {
+	_, err := ParseConfig([]byte(`
+rules:
+  include: [perf_does_not_exist]
+categories:
+  exclude: [gpu]
+severity:
+  perf_no_defer_in_loop: fatal
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown rule "perf_does_not_exist"`)
+	require.Contains(t, err.Error(), `unknown category "gpu"`)
+	require.Contains(t, err.Error(), `invalid severity "fatal"`)
+
+	_, err = ParseConfig([]byte("rule:\n  include: [perf_no_defer_in_loop]\n"))
+	require.Error(t, err, "misspelled keys must not be ignored")
+
+	cfg, err := ParseConfig(nil)
+	require.NoError(t, err)
+	require.True(t, cfg.Enabled("perf_no_defer_in_loop"))
+}
+
+func TestConfigRuleSelection(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+rules:
+  include: [perf_no_defer_in_loop]
+  exclude: [perf_use_buffered_io]
+categories:
+  include: [io, Concurrency]
+severity:
+  perf_bound_concurrency: "off"
+  perf_atomic_for_small_lock: error
+`))
+	require.NoError(t, err)
+
+	require.True(t, cfg.Enabled("perf_no_defer_in_loop"), "included by rule ID")
+	require.True(t, cfg.Enabled("perf_writer_prefer_bytes"), "included by category")
+	require.True(t, cfg.Enabled("perf_atomic_for_small_lock"), "category match is case-insensitive")
+	require.False(t, cfg.Enabled("perf_use_buffered_io"), "exclusions beat inclusions")
+	require.False(t, cfg.Enabled("perf_bound_concurrency"), "severity off disables the rule")
+	require.False(t, cfg.Enabled("perf_regex_compile_once"), "not selected")
+
+	require.Equal(t, "error", cfg.Severity("perf_atomic_for_small_lock"))
+	require.Equal(t, "warning", cfg.Severity("perf_no_defer_in_loop"))
+
+	var none *Config
+	require.True(t, none.Enabled("perf_regex_compile_once"))
+	require.Equal(t, "warning", none.Severity("perf_regex_compile_once"))
+}
+
+func TestConfigExcludedPaths(t *testing.T) {
+	cfg := &Config{
+		Dir:     filepath.FromSlash("/repo"),
+		Exclude: []string{"**/*_gen.go", "internal/legacy/**", "testdata/*.go", "mock_*.go"},
+	}
+	cases := map[string]bool{
+		"/repo/api/types_gen.go":          true,
+		"/repo/types_gen.go":              true,
+		"/repo/internal/legacy/a/b.go":    true,
+		"/repo/internal/legacyx/b.go":     false,
+		"/repo/testdata/fixture.go":       true,
+		"/repo/pkg/testdata/fixture.go":   false,
+		"/repo/pkg/mock_store.go":         true,
+		"/repo/pkg/store.go":              false,
+		"/elsewhere/internal/legacy/a.go": false,
+	}
+	for name, want := range cases {
+		require.Equal(t, want, cfg.Excluded(filepath.FromSlash(name)), name)
+	}
+}
+
+func TestFindConfigWalksUp(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b", "c")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+
+	found, err := FindConfig(nested)
+	require.NoError(t, err)
+	if found != "" {
+		require.False(t, strings.HasPrefix(found, root), "unexpected config %s", found)
+	}
+
+	want := filepath.Join(root, "a", ".perfcheck.yaml")
+	require.NoError(t, os.WriteFile(want, []byte("exclude: []\n"), 0o644))
+	found, err = FindConfig(nested)
+	require.NoError(t, err)
+	require.Equal(t, want, found)
+}
+
+func TestDiscoveredConfigFiltersDiagnostics(t *testing.T) {
+	root := t.TempDir()
+	filename := filepath.Join(root, "pkg", "sample.go")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".perfcheck.yaml"), []byte(`
+severity:
+  perf_no_defer_in_loop: error
+`), 0o644))
+	diags := runAnalyzerOnSource(t, deferInLoopAnalyzer, filename, deferLoopSource)
+	require.Len(t, diags, 1)
+	require.Equal(t, "perf_no_defer_in_loop", diags[0].Category)
+	cfg, err := ResolveConfig(filepath.Dir(filename), "")
+	require.NoError(t, err)
+	require.Equal(t, "error", cfg.Severity(diags[0].Category))
+
+	other := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(other, ".perfcheck.yml"), []byte(`
+exclude:
+  - "pkg/**"
+`), 0o644))
+	diags = runAnalyzerOnSource(t, deferInLoopAnalyzer, filepath.Join(other, "pkg", "sample.go"), deferLoopSource)
+	require.Empty(t, diags)
+}
+
+func TestBuildAppliesConfig(t *testing.T) {
+	cfg := &Config{Rules: Selection{Include: []string{"perf_no_defer_in_loop"}}}
+	bundle := Build(BuildOptions{Config: cfg})
+	require.Len(t, bundle.Analyzers, 1)
+	require.Equal(t, deferInLoopAnalyzer.Name, bundle.Analyzers[0].Name)
+
+	diags := runAnalyzerOnSource(t, bundle.Analyzers[0], "sample.go", deferLoopSource)
+	require.Len(t, diags, 1)
+
+	cfg.Rules.Exclude = []string{"perf_no_defer_in_loop"}
+	require.Empty(t, runAnalyzerOnSource(t, bundle.Analyzers[0], "sample.go", deferLoopSource))
+
+	require.Len(t, Build(BuildOptions{}).Analyzers, len(Analyzers()))
+}
+
+func TestBuildBundlesDoNotShareConfig(t *testing.T) {
+	strict := Build(BuildOptions{Config: &Config{Rules: Selection{
+		Include: []string{"perf_no_defer_in_loop", "perf_ignore_directive"},
+	}}})
+	relaxed := Build(BuildOptions{Config: &Config{Rules: Selection{Exclude: []string{"perf_no_defer_in_loop"}}}})
+
+	require.Len(t, runAnalyzerOnSource(t, strict.Analyzers[0], "sample.go", deferLoopSource), 1)
+	require.Len(t, strict.Analyzers[1].Requires, 3)
+	require.Len(t, relaxed.Analyzers[len(relaxed.Analyzers)-1].Requires, len(ruleAnalyzers())+1)
+	require.Len(t, ignoreDirectiveAnalyzer.Requires, len(ruleAnalyzers())+2)
+
+	src := `package sample
+
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		//perfcheck:ignore perf_no_defer_in_loop,perf_avoid_string_concat_loop reason="closed by caller"
+		defer f.Close()
+	}
+}
+`
+	rules, audit := runBundleWithDirectives(t, strict, src)
+	require.Empty(t, rules)
+	require.Empty(t, audit)
+
+	rules, audit = runBundleWithDirectives(t, relaxed, src)
+	require.Empty(t, rules)
+	require.Len(t, audit, 1)
+}
+
+func TestBuildKeepsDisabledRulesOutOfTheAuditRequirements(t *testing.T) {
+	bundle := Build(BuildOptions{Config: &Config{Rules: Selection{
+		Include: []string{"perf_no_defer_in_loop", "perf_ignore_directive"},
+	}}})
+	require.Len(t, bundle.Analyzers, 2)
+	require.Equal(t, deferInLoopAnalyzer.Name, bundle.Analyzers[0].Name)
+	require.Equal(t, ignoreDirectiveAnalyzer.Name, bundle.Analyzers[1].Name)
+
+	seen := make(map[string]bool)
+	var visit func(*analysis.Analyzer)
+	visit = func(a *analysis.Analyzer) {
+		if seen[a.Name] {
+			return
+		}
+		seen[a.Name] = true
+		for _, req := range a.Requires {
+			require.NotSame(t, configAnalyzer, req)
+			require.NotSame(t, profileAnalyzer, req)
+			visit(req)
+		}
+	}
+	for _, analyzer := range bundle.Analyzers {
+		visit(analyzer)
+	}
+	for analyzer, id := range analyzerRules {
+		require.Equal(t, id == "perf_no_defer_in_loop" || id == "perf_ignore_directive", seen[analyzer.Name], id)
+	}
+	require.Len(t, ignoreDirectiveAnalyzer.Requires, len(ruleAnalyzers())+2)
+}
+
+func TestIgnoreDirectiveAuditSkipsDisabledRules(t *testing.T) {
+	bundle := Build(BuildOptions{Config: &Config{Rules: Selection{Exclude: []string{"perf_no_defer_in_loop"}}}})
+
+	src := `package sample
+
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		//perfcheck:ignore perf_no_defer_in_loop reason="closed by caller"
+		defer f.Close()
+	}
+}
+`
+	rules, audit := runBundleWithDirectives(t, bundle, src)
+	require.Empty(t, rules)
+	require.Empty(t, audit)
+}
+
+func TestIgnoreDirectiveMatchingOnlyDisabledFindingsIsUnused(t *testing.T) {
+	bundle := Build(BuildOptions{Config: &Config{Rules: Selection{Exclude: []string{"perf_no_defer_in_loop"}}}})
+
+	src := `package sample
+
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		//perfcheck:ignore perf_no_defer_in_loop,perf_avoid_string_concat_loop reason="closed by caller"
+		defer f.Close()
+	}
+}
+`
+	rules, audit := runBundleWithDirectives(t, bundle, src)
+	require.Empty(t, rules)
+	require.Len(t, audit, 1)
+	require.Contains(t, audit[0].Message, "line-level perfcheck:ignore directive for "+
+		"perf_no_defer_in_loop,perf_avoid_string_concat_loop suppresses nothing; remove it")
+}
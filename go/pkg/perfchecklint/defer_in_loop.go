@@ -14,7 +14,7 @@ import (
 var deferInLoopAnalyzer = &analysis.Analyzer{
 	Name:     "perf_no_defer_in_loop",
 	Doc:      "reports defer statements inside loops",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_no_defer_in_loop")
 		if !ok {
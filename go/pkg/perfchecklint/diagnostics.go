@@ -11,11 +11,59 @@ import (
 )
 
 func report(pass *analysis.Pass, pos token.Pos, rule ruleset.Rule, detail string) {
-	pass.Report(analysis.Diagnostic{
-		Pos:      pos,
-		Message:  formatMessage(rule, detail),
-		Category: rule.Category,
-	})
+	emit(pass, analysis.Diagnostic{Pos: pos}, rule, detail)
+}
+
+// reportWithFixes reports a diagnostic spanning rng and attaches machine-applicable
+// rewrites so drivers such as `go vet -fix`, gopls, and golangci-lint can apply them.
+func reportWithFixes(
+	pass *analysis.Pass,
+	rng analysis.Range,
+	rule ruleset.Rule,
+	detail string,
+	fixes ...analysis.SuggestedFix,
+) {
+	emit(pass, analysis.Diagnostic{Pos: rng.Pos(), End: rng.End(), SuggestedFixes: fixes}, rule, detail)
+}
+
+// reportRelated reports a diagnostic at pos that also points at related
+// locations, such as the lock a blocking call is made under.
+func reportRelated(
+	pass *analysis.Pass,
+	pos token.Pos,
+	rule ruleset.Rule,
+	detail string,
+	related ...analysis.RelatedInformation,
+) {
+	emit(pass, analysis.Diagnostic{Pos: pos, Related: related}, rule, detail)
+}
+
+func emit(pass *analysis.Pass, diag analysis.Diagnostic, rule ruleset.Rule, detail string) {
+	// Filter by configuration first so a directive only counts as used when it
+	// hides a diagnostic that would otherwise have been reported.
+	cfg := passConfig(pass)
+	if !cfg.Enabled(rule.ID) || cfg.Excluded(pass.Fset.Position(diag.Pos).Filename) {
+		return
+	}
+	if index, ok := pass.ResultOf[directivesAnalyzer].(*directiveIndex); ok && index.suppress(pass.Fset, diag.Pos, rule.ID) {
+		return
+	}
+	diag.Message = formatMessage(rule, detail)
+	weighting, _ := requiredResult(pass, profileAnalyzer).(*hotness)
+	if name, share, ok := weighting.weigh(pass, diag.Pos); ok {
+		if weighting.dropCold && share <= weighting.cold {
+			return
+		}
+		diag.Message += fmt.Sprintf(" Profile: %s carries %.1f%% of %s samples.",
+			name, share, weighting.profile.SampleType)
+		if share >= weighting.hot {
+			diag.Message += " Hot path."
+		}
+	}
+	// The rule ID travels as the category so output formats never have to
+	// parse it back out of the message; severities come from the config.
+	diag.Category = rule.ID
+	pass.Report(diag)
 }
 
 func formatMessage(rule ruleset.Rule, detail string) string {
@@ -35,3 +83,27 @@ func normalizeSentence(text string) string {
 	}
 	return text + "."
 }
+
+// passConfig returns the configuration resolved for the package pass analyzes.
+func passConfig(pass *analysis.Pass) *Config {
+	cfg, _ := requiredResult(pass, configAnalyzer).(*Config)
+	return cfg
+}
+
+// requiredResult returns the result of the requirement standing in for a.
+// Bundles built by Build replace configAnalyzer and profileAnalyzer with
+// analyzers of the same name bound to the bundle's options.
+func requiredResult(pass *analysis.Pass, a *analysis.Analyzer) any {
+	if result, ok := pass.ResultOf[a]; ok {
+		return result
+	}
+	if pass.Analyzer == nil {
+		return nil
+	}
+	for _, req := range pass.Analyzer.Requires {
+		if req.Name == a.Name {
+			return pass.ResultOf[req]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+const ignoreDirectivePrefix = "//perfcheck:ignore"
+
+// directiveNow is the clock used to expire directives; tests replace it.
+var directiveNow = time.Now
+
+// directivesAnalyzer parses //perfcheck:ignore directives once per package so
+// every rule analyzer can consult the same suppression index.
+var directivesAnalyzer = &analysis.Analyzer{
+	Name:       "perfcheck_directives",
+	Doc:        "parses //perfcheck:ignore suppression directives",
+	ResultType: reflect.TypeOf((*directiveIndex)(nil)),
+	Run: func(pass *analysis.Pass) (any, error) {
+		index := &directiveIndex{}
+		for _, file := range pass.Files {
+			index.directives = append(index.directives, parseFileDirectives(pass.Fset, file)...)
+		}
+		return index, nil
+	},
+}
+
+// ignoreDirectiveAnalyzer audits suppression directives after every rule
+// analyzer has run, so stale or unjustified ignores cannot accumulate.
+var ignoreDirectiveAnalyzer = &analysis.Analyzer{
+	Name: "perf_ignore_directive",
+	Doc:  "reports //perfcheck:ignore directives that are unjustified, expired, or unused",
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_ignore_directive")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_ignore_directive not found")
+		}
+
+		index, _ := pass.ResultOf[directivesAnalyzer].(*directiveIndex)
+		if index == nil {
+			return nil, fmt.Errorf("missing directives dependency")
+		}
+
+		auditDirectives(pass, index, passConfig(pass), rule)
+		return nil, nil
+	},
+}
+
+func init() {
+	ignoreDirectiveAnalyzer.Requires = append(ruleAnalyzers(), directivesAnalyzer, configAnalyzer)
+}
+
+type directiveScope int
+
+const (
+	scopeLine directiveScope = iota
+	scopeDecl
+	scopeFile
+)
+
+func (s directiveScope) String() string {
+	switch s {
+	case scopeDecl:
+		return "declaration"
+	case scopeFile:
+		return "file"
+	default:
+		return "line"
+	}
+}
+
+// ignoreDirective is a single parsed //perfcheck:ignore comment.
+type ignoreDirective struct {
+	pos      token.Pos
+	rules    []string
+	reason   string
+	until    time.Time
+	hasUntil bool
+	problem  string
+
+	scope    directiveScope
+	filename string
+	line     int
+	from, to token.Pos
+
+	used bool
+}
+
+func (d *ignoreDirective) expired(now time.Time) bool {
+	if !d.hasUntil {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return today.After(d.until)
+}
+
+func (d *ignoreDirective) matches(fset *token.FileSet, pos token.Pos, ruleID string) bool {
+	if !slices.Contains(d.rules, ruleID) {
+		return false
+	}
+	if d.scope != scopeLine {
+		return d.from <= pos && pos <= d.to
+	}
+	position := fset.Position(pos)
+	return position.Filename == d.filename && position.Line == d.line
+}
+
+// directiveIndex is shared by all analyzers of a package; suppress records
+// which directives silenced a diagnostic so the audit can flag unused ones.
+type directiveIndex struct {
+	mu         sync.Mutex
+	directives []*ignoreDirective
+}
+
+func (idx *directiveIndex) suppress(fset *token.FileSet, pos token.Pos, ruleID string) bool {
+	if idx == nil || !pos.IsValid() {
+		return false
+	}
+	now := directiveNow()
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	suppressed := false
+	for _, d := range idx.directives {
+		if d.problem != "" || d.expired(now) || !d.matches(fset, pos, ruleID) {
+			continue
+		}
+		d.used = true
+		suppressed = true
+	}
+	return suppressed
+}
+
+func parseFileDirectives(fset *token.FileSet, file *ast.File) []*ignoreDirective {
+	var out []*ignoreDirective
+	codeLines := codeLineSet(fset, file)
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !strings.HasPrefix(comment.Text, ignoreDirectivePrefix) {
+				continue
+			}
+			rest := strings.TrimPrefix(comment.Text, ignoreDirectivePrefix)
+			if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+				continue
+			}
+			d := parseDirective(rest)
+			d.pos = comment.Pos()
+			placeDirective(fset, file, group, comment, codeLines, d)
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// parseDirective parses `<rule>[,<rule>] reason="..." until=YYYY-MM-DD`.
+func parseDirective(text string) *ignoreDirective {
+	d := &ignoreDirective{}
+	fields, err := splitDirectiveFields(text)
+	if err != nil {
+		d.problem = err.Error()
+		return d
+	}
+	if len(fields) == 0 || strings.Contains(fields[0], "=") {
+		d.problem = "names no rule"
+		return d
+	}
+	for _, id := range strings.Split(fields[0], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			d.rules = append(d.rules, id)
+		}
+	}
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			d.problem = fmt.Sprintf("has unexpected argument %q", field)
+			return d
+		}
+		switch key {
+		case "reason":
+			d.reason = strings.TrimSpace(value)
+		case "until":
+			until, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				d.problem = fmt.Sprintf("has invalid until date %q; use YYYY-MM-DD", value)
+				return d
+			}
+			d.until, d.hasUntil = until, true
+		default:
+			d.problem = fmt.Sprintf("has unknown key %q", key)
+			return d
+		}
+	}
+	return d
+}
+
+// splitDirectiveFields splits on whitespace while keeping double-quoted values
+// (with backslash escapes) intact and unquoted.
+func splitDirectiveFields(text string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inQuotes, escaped, pending := false, false, false
+	for _, r := range text {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case inQuotes && r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+			pending = true
+		case !inQuotes && (r == ' ' || r == '\t'):
+			if pending || current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+				pending = false
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("has an unterminated quoted value")
+	}
+	if pending || current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// placeDirective resolves the region a directive covers: the whole file when
+// it precedes the package clause, the declaration whose doc comment holds it,
+// otherwise its own line when trailing code or the next line when standalone.
+func placeDirective(
+	fset *token.FileSet,
+	file *ast.File,
+	group *ast.CommentGroup,
+	comment *ast.Comment,
+	codeLines map[int]bool,
+	d *ignoreDirective,
+) {
+	if comment.End() < file.Package {
+		d.scope, d.from, d.to = scopeFile, file.FileStart, file.FileEnd
+		return
+	}
+	for _, decl := range file.Decls {
+		var doc *ast.CommentGroup
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			doc = decl.Doc
+		case *ast.GenDecl:
+			doc = decl.Doc
+		}
+		if doc == group {
+			d.scope, d.from, d.to = scopeDecl, decl.Pos(), decl.End()
+			return
+		}
+	}
+	position := fset.Position(comment.Pos())
+	d.scope, d.filename, d.line = scopeLine, position.Filename, position.Line
+	if !codeLines[position.Line] {
+		d.line = fset.Position(group.End()).Line + 1
+	}
+}
+
+// codeLineSet records the lines on which syntax nodes start or end, which is
+// enough to tell trailing comments apart from comments on their own line.
+func codeLineSet(fset *token.FileSet, file *ast.File) map[int]bool {
+	lines := make(map[int]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n.(type) {
+		case nil, *ast.CommentGroup, *ast.Comment:
+			return false
+		}
+		lines[fset.Position(n.Pos()).Line] = true
+		lines[fset.Position(n.End()).Line] = true
+		return true
+	})
+	return lines
+}
+
+func auditDirectives(pass *analysis.Pass, index *directiveIndex, cfg *Config, rule ruleset.Rule) {
+	reg := ruleset.MustDefault()
+	now := directiveNow()
+
+	index.mu.Lock()
+	directives := append([]*ignoreDirective(nil), index.directives...)
+	index.mu.Unlock()
+	sort.Slice(directives, func(i, j int) bool { return directives[i].pos < directives[j].pos })
+
+	for _, d := range directives {
+		if d.problem != "" {
+			report(pass, d.pos, rule, "perfcheck:ignore directive "+d.problem)
+			continue
+		}
+		unknown := false
+		for _, id := range d.rules {
+			known, ok := reg.RuleByID(id)
+			if !ok || !slices.Contains(known.Langs, "go") {
+				report(pass, d.pos, rule, fmt.Sprintf("perfcheck:ignore directive names unknown Go rule %q", id))
+				unknown = true
+			}
+		}
+		if unknown {
+			continue
+		}
+		if d.reason == "" {
+			report(pass, d.pos, rule, `perfcheck:ignore directive is missing reason="..."`)
+		}
+		switch {
+		case d.expired(now):
+			report(
+				pass,
+				d.pos,
+				rule,
+				fmt.Sprintf("perfcheck:ignore directive expired on %s and no longer suppresses diagnostics",
+					d.until.Format(time.DateOnly)),
+			)
+		case !d.used && slices.ContainsFunc(d.rules, cfg.Enabled):
+			// Directives for rules the project configuration disables cannot
+			// suppress anything, but they become live again once re-enabled.
+			report(
+				pass,
+				d.pos,
+				rule,
+				fmt.Sprintf("%s-level perfcheck:ignore directive for %s suppresses nothing; remove it",
+					d.scope, strings.Join(d.rules, ",")),
+			)
+		}
+	}
+}
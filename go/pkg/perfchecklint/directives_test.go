@@ -0,0 +1,175 @@
+package perfchecklint
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestIgnoreDirectiveSuppressesNextLine(t *testing.T) {
+	src := `package sample
+
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		//perfcheck:ignore perf_no_defer_in_loop reason="closed by caller on panic"
+		defer f.Close()
+	}
+}
+`
+	rules, audit := runWithDirectives(t, src)
+	require.Empty(t, rules)
+	require.Empty(t, audit)
+}
+
+func TestIgnoreDirectiveSuppressesTrailingLine(t *testing.T) {
+	src := `package sample
+
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		defer f.Close() //perfcheck:ignore perf_no_defer_in_loop reason="bounded by caller"
+	}
+	for _, f := range files {
+		defer f.Close()
+	}
+}
+`
+	rules, audit := runWithDirectives(t, src)
+	require.Len(t, rules, 1)
+	require.True(t, containsRule(rules, "perf_no_defer_in_loop"))
+	require.Empty(t, audit)
+}
+
+func TestIgnoreDirectiveFunctionAndFileScopes(t *testing.T) {
+	src := `//perfcheck:ignore perf_avoid_string_concat_loop reason="generated code" until=2099-12-31
+
+package sample
+
+// closeAll defers by design.
+//
+//perfcheck:ignore perf_no_defer_in_loop,perf_bound_concurrency reason="tiny fan-out"
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		defer f.Close()
+		go f.Close()
+	}
+}
+
+func join(items []string) string {
+	out := ""
+	for _, item := range items {
+		out += item
+	}
+	return out
+}
+`
+	rules, audit := runWithDirectives(t, src)
+	require.Empty(t, rules)
+	require.Empty(t, audit)
+}
+
+func TestIgnoreDirectiveReportsHygieneProblems(t *testing.T) {
+	restore := directiveNow
+	directiveNow = func() time.Time { return time.Date(2027, time.March, 1, 12, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() { directiveNow = restore })
+
+	src := `package sample
+
+func closeAll(files []interface{ Close() error }) {
+	for _, f := range files {
+		//perfcheck:ignore perf_no_defer_in_loop
+		defer f.Close()
+	}
+	for _, f := range files {
+		//perfcheck:ignore perf_no_defer_in_loop reason="legacy" until=2027-01-01
+		defer f.Close()
+	}
+	//perfcheck:ignore perf_regex_compile_once reason="nothing to silence"
+	for range files {
+	}
+	//perfcheck:ignore perf_does_not_exist reason="typo"
+	_ = files
+	//perfcheck:ignore perf_no_defer_in_loop until=soon
+	_ = files
+}
+`
+	rules, audit := runWithDirectives(t, src)
+	require.Len(t, rules, 1, "expired directive must stop suppressing")
+
+	messages := make([]string, 0, len(audit))
+	for _, diag := range audit {
+		require.Equal(t, "perf_ignore_directive", extractRuleID(diag.Message))
+		messages = append(messages, diag.Message)
+	}
+	require.Len(t, messages, 5)
+	require.Contains(t, messages[0], `missing reason="..."`)
+	require.Contains(t, messages[1], "expired on 2027-01-01")
+	require.Contains(t, messages[2], "suppresses nothing")
+	require.Contains(t, messages[3], `unknown Go rule "perf_does_not_exist"`)
+	require.Contains(t, messages[4], `invalid until date "soon"`)
+}
+
+func TestParseDirectiveHandlesQuotedReasons(t *testing.T) {
+	d := parseDirective(` perf_a,perf_b reason="keeps \"legacy\" API" until=2030-05-01`)
+	require.Empty(t, d.problem)
+	require.Equal(t, []string{"perf_a", "perf_b"}, d.rules)
+	require.Equal(t, `keeps "legacy" API`, d.reason)
+	require.True(t, d.hasUntil)
+	require.Equal(t, "2030-05-01", d.until.Format(time.DateOnly))
+
+	require.NotEmpty(t, parseDirective(` reason="no rule"`).problem)
+	require.NotEmpty(t, parseDirective(` perf_a reason="unterminated`).problem)
+}
+
+// runWithDirectives runs every analyzer over src like a driver would, returning
+// the rule diagnostics and the directive audit diagnostics separately.
+func runWithDirectives(t *testing.T, src string) ([]analysis.Diagnostic, []analysis.Diagnostic) {
+	t.Helper()
+
+	return runBundleWithDirectives(t, Bundle{Analyzers: Analyzers()}, src)
+}
+
+// runBundleWithDirectives is runWithDirectives for the analyzers of bundle.
+func runBundleWithDirectives(t *testing.T, bundle Bundle, src string) ([]analysis.Diagnostic, []analysis.Diagnostic) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "directives.go", src, parser.ParseComments)
+	require.NoError(t, err)
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("sample", fset, []*ast.File{file}, info)
+	require.NoError(t, err)
+
+	files := []*ast.File{file}
+	memo := make(map[*analysis.Analyzer]any)
+	var rules, audit []analysis.Diagnostic
+	for _, analyzer := range bundle.Analyzers {
+		if analyzer.Name == ignoreDirectiveAnalyzer.Name {
+			continue
+		}
+		diags, result := runAnalyzerGraph(t, analyzer, fset, files, info, pkg, memo)
+		memo[analyzer] = result
+		rules = append(rules, diags...)
+	}
+	for _, analyzer := range bundle.Analyzers {
+		if analyzer.Name == ignoreDirectiveAnalyzer.Name {
+			audit, _ = runAnalyzerGraph(t, analyzer, fset, files, info, pkg, memo)
+		}
+	}
+	return rules, audit
+}
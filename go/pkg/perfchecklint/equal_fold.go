@@ -3,7 +3,10 @@ package perfchecklint
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
+	"go/types"
+	"strings"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
@@ -15,7 +18,7 @@ import (
 var equalFoldAnalyzer = &analysis.Analyzer{
 	Name:     "perf_equal_fold_compare",
 	Doc:      "reports case-insensitive comparisons built via ToLower/ToUpper",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_equal_fold_compare")
 		if !ok {
@@ -32,18 +35,93 @@ var equalFoldAnalyzer = &analysis.Analyzer{
 			if !ok {
 				return
 			}
-			if bin.Op != token.EQL && bin.Op != token.NEQ {
-				return
-			}
-			if isStringsNormalizeCall(pass, bin.X) || isStringsNormalizeCall(pass, bin.Y) {
-				report(pass, bin.Pos(), rule, "use strings.EqualFold for case-insensitive comparison")
-			}
+			checkEqualFoldCompare(pass, bin, rule)
 		})
 
 		return nil, nil
 	},
 }
 
+func checkEqualFoldCompare(pass *analysis.Pass, bin *ast.BinaryExpr, rule ruleset.Rule) {
+	if bin.Op != token.EQL && bin.Op != token.NEQ {
+		return
+	}
+	if !isStringsNormalizeCall(pass, bin.X) && !isStringsNormalizeCall(pass, bin.Y) {
+		return
+	}
+	const detail = "use strings.EqualFold for case-insensitive comparison"
+	fix, ok := equalFoldFix(pass, bin)
+	if !ok {
+		report(pass, bin.Pos(), rule, detail)
+		return
+	}
+	reportWithFixes(pass, bin, rule, detail, fix)
+}
+
+// equalFoldFix rewrites the comparison into strings.EqualFold when doing so
+// preserves behavior: both operands must be normalized with the same function,
+// or one side must be a constant already in the normalized form.
+func equalFoldFix(pass *analysis.Pass, bin *ast.BinaryExpr) (analysis.SuggestedFix, bool) {
+	left, leftFn, leftQual := normalizeOperand(pass, bin.X)
+	right, rightFn, rightQual := normalizeOperand(pass, bin.Y)
+
+	fn, qualifier := leftFn, leftQual
+	switch {
+	case leftFn != "" && rightFn != "":
+		if leftFn != rightFn {
+			return analysis.SuggestedFix{}, false
+		}
+	case leftFn != "":
+		if !isNormalizedConstant(pass, bin.Y, leftFn) {
+			return analysis.SuggestedFix{}, false
+		}
+	case rightFn != "":
+		fn, qualifier = rightFn, rightQual
+		if !isNormalizedConstant(pass, bin.X, rightFn) {
+			return analysis.SuggestedFix{}, false
+		}
+	default:
+		return analysis.SuggestedFix{}, false
+	}
+
+	prefix := qualifier + ".EqualFold("
+	if bin.Op == token.NEQ {
+		prefix = "!" + prefix
+	}
+	return analysis.SuggestedFix{
+		Message: fmt.Sprintf("Replace %s comparison with %s.EqualFold", fn, qualifier),
+		TextEdits: []analysis.TextEdit{
+			{Pos: bin.Pos(), End: left.Pos(), NewText: []byte(prefix)},
+			{Pos: left.End(), End: right.Pos(), NewText: []byte(", ")},
+			{Pos: right.End(), End: bin.End(), NewText: []byte(")")},
+		},
+	}, true
+}
+
+// normalizeOperand unwraps strings.ToLower/ToUpper calls, returning the inner
+// argument, the normalizing function name, and the package qualifier used at
+// the call site. Other expressions are returned unchanged with an empty name.
+func normalizeOperand(pass *analysis.Pass, expr ast.Expr) (ast.Expr, string, string) {
+	if !isStringsNormalizeCall(pass, expr) {
+		return expr, "", ""
+	}
+	call, _ := expr.(*ast.CallExpr)
+	sel, _ := call.Fun.(*ast.SelectorExpr)
+	return call.Args[0], sel.Sel.Name, types.ExprString(sel.X)
+}
+
+func isNormalizedConstant(pass *analysis.Pass, expr ast.Expr, fn string) bool {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return false
+	}
+	value := constant.StringVal(tv.Value)
+	if fn == "ToUpper" {
+		return strings.ToUpper(value) == value
+	}
+	return strings.ToLower(value) == value
+}
+
 func isStringsNormalizeCall(pass *analysis.Pass, expr ast.Expr) bool {
 	call, ok := expr.(*ast.CallExpr)
 	if !ok {
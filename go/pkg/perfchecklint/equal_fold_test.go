@@ -41,7 +41,85 @@ func equal(a, b string) bool {
 	require.Empty(t, diags)
 }
 
+func TestEqualFoldAnalyzerSuggestsFixes(t *testing.T) {
+	cases := []struct {
+		name string
+		expr string
+		want string
+	}{
+		{
+			name: "both sides lowered",
+			expr: "strings.ToLower(a) == strings.ToLower(b)",
+			want: "strings.EqualFold(a, b)",
+		},
+		{
+			name: "inequality negates",
+			expr: "strings.ToUpper(a) != strings.ToUpper(b)",
+			want: "!strings.EqualFold(a, b)",
+		},
+		{
+			name: "normalized constant on the right",
+			expr: `strings.ToLower(a) == "gopher"`,
+			want: `strings.EqualFold(a, "gopher")`,
+		},
+		{
+			name: "normalized constant on the left",
+			expr: `"GOPHER" != strings.ToUpper(b)`,
+			want: `!strings.EqualFold("GOPHER", b)`,
+		},
+		{
+			name: "named constant",
+			expr: "strings.ToLower(a) == lowered",
+			want: "strings.EqualFold(a, lowered)",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := equalFoldSource(tc.expr)
+			fset, diags := runEqualFoldWithFileSet(src)
+			require.Len(t, diags, 1)
+			require.Len(t, diags[0].SuggestedFixes, 1)
+			require.Equal(t, equalFoldSource(tc.want), applySuggestedFix(t, fset, src, diags[0]))
+		})
+	}
+}
+
+func TestEqualFoldAnalyzerSkipsFixWhenNotEquivalent(t *testing.T) {
+	cases := []string{
+		`strings.ToLower(a) == "Gopher"`,
+		"strings.ToLower(a) == b",
+		"strings.ToLower(a) == strings.ToUpper(b)",
+	}
+
+	for _, expr := range cases {
+		t.Run(expr, func(t *testing.T) {
+			_, diags := runEqualFoldWithFileSet(equalFoldSource(expr))
+			require.Len(t, diags, 1)
+			require.Empty(t, diags[0].SuggestedFixes)
+		})
+	}
+}
+
+func equalFoldSource(expr string) string {
+	return `package sample
+
+import "strings"
+
+const lowered = "gopher"
+
+func equal(a, b string) bool {
+	return ` + expr + `
+}
+`
+}
+
 func runEqualFold(src string) []analysis.Diagnostic {
+	_, diags := runEqualFoldWithFileSet(src)
+	return diags
+}
+
+func runEqualFoldWithFileSet(src string) (*token.FileSet, []analysis.Diagnostic) {
 	fset := token.NewFileSet()
 	file, err := parser.ParseFile(fset, "equalfold.go", src, parser.ParseComments)
 	if err != nil {
@@ -75,18 +153,11 @@ func runEqualFold(src string) []analysis.Diagnostic {
 	}
 
 	ast.Inspect(file, func(n ast.Node) bool {
-		bin, ok := n.(*ast.BinaryExpr)
-		if !ok {
-			return true
-		}
-		if bin.Op != token.EQL && bin.Op != token.NEQ {
-			return true
-		}
-		if isStringsNormalizeCall(&pass, bin.X) || isStringsNormalizeCall(&pass, bin.Y) {
-			report(&pass, bin.Pos(), rule, "use strings.EqualFold for case-insensitive comparison")
+		if bin, ok := n.(*ast.BinaryExpr); ok {
+			checkEqualFoldCompare(&pass, bin, rule)
 		}
 		return true
 	})
 
-	return diags
+	return fset, diags
 }
@@ -0,0 +1,151 @@
+package perfchecklint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// escapeSite is a source position the compiler's escape analysis reports on.
+type escapeSite struct {
+	file      string
+	line, col int
+}
+
+// escapeDecisions records which allocation sites the compiler moves to the
+// heap, parsed from `-gcflags=-m` output. The value is the last flow step
+// behind the decision when the output came from -m=2, and empty otherwise.
+type escapeDecisions map[escapeSite]string
+
+// lookup returns whether the compiler moved the allocation at pos to the heap
+// and, when known, why.
+func (d escapeDecisions) lookup(fset *token.FileSet, pos token.Pos) (string, bool) {
+	position := fset.Position(pos)
+	file := position.Filename
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	reason, ok := d[escapeSite{file: file, line: position.Line, col: position.Column}]
+	return reason, ok
+}
+
+// parseEscapeLog reads compiler diagnostics such as
+//
+//	./a.go:8:9: &point{...} escapes to heap in newPoint:
+//	./a.go:8:9:     from return &point{...} (return) at ./a.go:8:2
+//	./a.go:18:2: moved to heap: v
+//
+// resolving relative file names against dir. Sites reported as not escaping
+// and unrelated diagnostics like inlining decisions are ignored.
+func parseEscapeLog(data []byte, dir string) escapeDecisions {
+	decisions := make(escapeDecisions)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		site, msg, ok := splitCompilerLine(scanner.Text(), dir)
+		if !ok {
+			continue
+		}
+		trimmed := strings.TrimSpace(msg)
+		switch {
+		case strings.HasPrefix(trimmed, "from ") && msg != trimmed:
+			if _, escaped := decisions[site]; escaped {
+				decisions[site] = compactReason(strings.TrimPrefix(trimmed, "from "))
+			}
+		case strings.HasPrefix(msg, "moved to heap: "),
+			strings.HasSuffix(msg, " escapes to heap"),
+			strings.Contains(msg, " escapes to heap in ") && strings.HasSuffix(msg, ":"):
+			if _, seen := decisions[site]; !seen {
+				decisions[site] = ""
+			}
+		}
+	}
+	return decisions
+}
+
+// splitCompilerLine splits "file:line:col: message" into its site and
+// message.
+func splitCompilerLine(line, dir string) (escapeSite, string, bool) {
+	parts := strings.SplitN(line, ":", 4)
+	if len(parts) != 4 || !strings.HasPrefix(parts[3], " ") {
+		return escapeSite{}, "", false
+	}
+	lineNo, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return escapeSite{}, "", false
+	}
+	col, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return escapeSite{}, "", false
+	}
+	file := parts[0]
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	return escapeSite{file: filepath.Clean(file), line: lineNo, col: col}, parts[3][1:], true
+}
+
+// compactReason shortens file names in a flow step such as
+// "return &point{...} (return) at ./a.go:8:2" to their base name.
+func compactReason(step string) string {
+	at := strings.LastIndex(step, " at ")
+	if at < 0 {
+		return step
+	}
+	return step[:at+4] + filepath.Base(step[at+4:])
+}
+
+var escapeLogCache sync.Map // absolute path -> *escapeLogEntry
+
+type escapeLogEntry struct {
+	once      sync.Once
+	decisions escapeDecisions
+	err       error
+}
+
+// cachedEscapeLog parses a saved `go build -gcflags=-m` log once per process.
+// Relative file names in the log are resolved against the log's directory,
+// so save it from the directory the build ran in.
+func cachedEscapeLog(filename string) (escapeDecisions, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := escapeLogCache.LoadOrStore(abs, &escapeLogEntry{})
+	entry, _ := value.(*escapeLogEntry)
+	entry.once.Do(func() {
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			entry.err = fmt.Errorf("escape log: %w", err)
+			return
+		}
+		entry.decisions = parseEscapeLog(data, filepath.Dir(abs))
+	})
+	return entry.decisions, entry.err
+}
+
+// compilerEscapeDecisions compiles the package of pass with -gcflags=-m=2 and
+// parses the escape analysis it prints. The go command replays compiler
+// output from its build cache, so repeated runs stay cheap.
+func compilerEscapeDecisions(pass *analysis.Pass) (escapeDecisions, error) {
+	dir := packageDir(pass)
+	if dir == "" {
+		return nil, fmt.Errorf("escape analysis: cannot locate the source directory of %s", pass.Pkg.Path())
+	}
+	cmd := exec.Command("go", "build", "-gcflags=-m=2", "-o", os.DevNull, ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("escape analysis of %s: %w\n%s", pass.Pkg.Path(), err, out)
+	}
+	return parseEscapeLog(out, dir), nil
+}
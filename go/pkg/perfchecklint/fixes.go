@@ -0,0 +1,166 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// fileFor returns the syntax tree in the pass that contains pos.
+func fileFor(pass *analysis.Pass, pos token.Pos) *ast.File {
+	for _, file := range pass.Files {
+		if file.FileStart <= pos && pos <= file.FileEnd {
+			return file
+		}
+	}
+	return nil
+}
+
+// enclosingPath returns the AST path from the innermost node covering node up
+// to the file root.
+func enclosingPath(pass *analysis.Pass, node ast.Node) []ast.Node {
+	file := fileFor(pass, node.Pos())
+	if file == nil {
+		return nil
+	}
+	path, _ := astutil.PathEnclosingInterval(file, node.Pos(), node.End())
+	return path
+}
+
+// enclosingFunc returns the body of the innermost function literal or
+// declaration on path.
+func enclosingFunc(path []ast.Node) *ast.BlockStmt {
+	for _, node := range path {
+		switch fn := node.(type) {
+		case *ast.FuncLit:
+			return fn.Body
+		case *ast.FuncDecl:
+			return fn.Body
+		}
+	}
+	return nil
+}
+
+// statementAnchor returns the node that new statements must be inserted in
+// front of so they run before stmt: labeled statements keep their label
+// attached to the loop.
+func statementAnchor(path []ast.Node, stmt ast.Stmt) ast.Node {
+	var anchor ast.Node = stmt
+	for _, node := range path {
+		if node == stmt {
+			continue
+		}
+		labeled, ok := node.(*ast.LabeledStmt)
+		if !ok || labeled.Stmt != anchor {
+			break
+		}
+		anchor = labeled
+	}
+	return anchor
+}
+
+// lineIndent reconstructs the gofmt indentation of the line holding pos.
+func lineIndent(pass *analysis.Pass, pos token.Pos) string {
+	col := pass.Fset.Position(pos).Column
+	if col <= 1 {
+		return ""
+	}
+	return strings.Repeat("\t", col-1)
+}
+
+// importQualifier returns the identifier that refers to the package at path
+// from pos, adding an import edit when the file does not import it yet. It
+// reports false when the package name is shadowed at pos.
+func importQualifier(
+	pass *analysis.Pass,
+	file *ast.File,
+	pos token.Pos,
+	path string,
+) (string, []analysis.TextEdit, bool) {
+	for _, spec := range file.Imports {
+		value, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || value != path {
+			continue
+		}
+		obj := pass.TypesInfo.Implicits[spec]
+		if spec.Name != nil {
+			obj = pass.TypesInfo.Defs[spec.Name]
+		}
+		pkgName, ok := obj.(*types.PkgName)
+		if !ok || pkgName.Name() == "_" || pkgName.Name() == "." {
+			continue
+		}
+		if !nameResolvesTo(pass, pos, pkgName.Name(), pkgName) {
+			return "", nil, false
+		}
+		return pkgName.Name(), nil, true
+	}
+
+	name := path[strings.LastIndex(path, "/")+1:]
+	if nameResolvesTo(pass, pos, name, nil) || fileDeclares(pass, file, name) {
+		return "", nil, false
+	}
+	return name, []analysis.TextEdit{addImportEdit(file, path)}, true
+}
+
+func nameResolvesTo(pass *analysis.Pass, pos token.Pos, name string, want types.Object) bool {
+	if pass.Pkg == nil {
+		return want == nil
+	}
+	scope := pass.Pkg.Scope().Innermost(pos)
+	if scope == nil {
+		scope = pass.Pkg.Scope()
+	}
+	_, obj := scope.LookupParent(name, pos)
+	if want == nil {
+		return obj != nil
+	}
+	return obj == want
+}
+
+func fileDeclares(pass *analysis.Pass, file *ast.File, name string) bool {
+	scope := pass.TypesInfo.Scopes[file]
+	return scope != nil && scope.Lookup(name) != nil
+}
+
+func addImportEdit(file *ast.File, path string) analysis.TextEdit {
+	quoted := strconv.Quote(path)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			return analysis.TextEdit{Pos: gen.Lparen + 1, End: gen.Lparen + 1, NewText: []byte("\n\t" + quoted)}
+		}
+		return analysis.TextEdit{Pos: gen.End(), End: gen.End(), NewText: []byte("\nimport " + quoted)}
+	}
+	end := file.Name.End()
+	return analysis.TextEdit{Pos: end, End: end, NewText: []byte("\n\nimport " + quoted)}
+}
+
+// freshName derives an identifier from base that neither resolves at pos nor
+// appears anywhere inside within, so inserted code cannot capture or be
+// shadowed by existing declarations.
+func freshName(pass *analysis.Pass, pos token.Pos, within ast.Node, base string) string {
+	used := make(map[string]bool)
+	if within != nil {
+		ast.Inspect(within, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+			return true
+		})
+	}
+	name := base
+	for i := 2; used[name] || nameResolvesTo(pass, pos, name, nil); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	return name
+}
@@ -1,6 +1,7 @@
 package perfchecklint
 
 import (
+	"bytes"
 	"go/ast"
 	"go/importer"
 	"go/parser"
@@ -8,8 +9,12 @@ import (
 	"go/types"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"golang.org/x/tools/go/analysis"
@@ -46,20 +51,34 @@ func TestViolationsFixtureTriggersAllRules(t *testing.T) {
 	}
 
 	expected := map[string]bool{
-		"perf_avoid_string_concat_loop": false,
-		"perf_regex_compile_once":       false,
-		"perf_preallocate_collections":  false,
-		"perf_avoid_reflection_dynamic": false,
-		"perf_bound_concurrency":        false,
-		"perf_equal_fold_compare":       false,
-		"perf_syncpool_store_pointers":  false,
-		"perf_writer_prefer_bytes":      false,
-		"perf_avoid_linked_list":        false,
-		"perf_atomic_for_small_lock":    false,
-		"perf_no_defer_in_loop":         false,
-		"perf_avoid_rune_conversion":    false,
-		"perf_use_buffered_io":          false,
-		"perf_prefer_stack_alloc":       false,
+		"perf_avoid_string_concat_loop":     false,
+		"perf_regex_compile_once":           false,
+		"perf_preallocate_collections":      false,
+		"perf_avoid_reflection_dynamic":     false,
+		"perf_bound_concurrency":            false,
+		"perf_equal_fold_compare":           false,
+		"perf_syncpool_store_pointers":      false,
+		"perf_writer_prefer_bytes":          false,
+		"perf_avoid_linked_list":            false,
+		"perf_atomic_for_small_lock":        false,
+		"perf_no_defer_in_loop":             false,
+		"perf_avoid_rune_conversion":        false,
+		"perf_use_buffered_io":              false,
+		"perf_prefer_stack_alloc":           false,
+		"perf_inline_byte_to_string_mapkey": false,
+		"perf_hoist_loop_invariant_call":    false,
+		"perf_batch_syscalls_cgo":           false,
+		"perf_prefer_strconv":               false,
+		"perf_builder_grow":                 false,
+		"perf_reuse_timers":                 false,
+		"perf_no_blocking_under_lock":       false,
+		"perf_range_value_copy":             false,
+		"perf_large_param_copy":             false,
+		"perf_avoid_interface_boxing":       false,
+		"perf_avoid_codec_reflection_loop":  false,
+		"perf_reuse_http_client":            false,
+		"perf_avoid_n_plus_one_query":       false,
+		"perf_ignore_directive":             false,
 	}
 
 	for _, analyzer := range All() {
@@ -97,11 +116,33 @@ func runAnalyzerOnFixture(
 ) []analysis.Diagnostic {
 	t.Helper()
 
+	diags, _ := runAnalyzerGraph(t, analyzer, fset, []*ast.File{file}, info, pkg, nil)
+	return diags
+}
+
+// runAnalyzerGraph runs analyzer after its transitive requirements, sharing
+// results through memo so dependencies run once per package like a real driver.
+// Only the diagnostics of analyzer itself are returned.
+func runAnalyzerGraph(
+	t *testing.T,
+	analyzer *analysis.Analyzer,
+	fset *token.FileSet,
+	files []*ast.File,
+	info *types.Info,
+	pkg *types.Package,
+	memo map[*analysis.Analyzer]any,
+) ([]analysis.Diagnostic, any) {
+	t.Helper()
+
+	if memo == nil {
+		memo = make(map[*analysis.Analyzer]any)
+	}
+
 	var diags []analysis.Diagnostic
 	pass := analysis.Pass{
 		Analyzer:   analyzer,
 		Fset:       fset,
-		Files:      []*ast.File{file},
+		Files:      files,
 		Pkg:        pkg,
 		TypesInfo:  info,
 		TypesSizes: types.SizesFor("gc", runtime.GOARCH),
@@ -109,23 +150,73 @@ func runAnalyzerOnFixture(
 		Report: func(d analysis.Diagnostic) {
 			diags = append(diags, d)
 		},
+		ImportObjectFact: importTestFact,
+		ExportObjectFact: exportTestFact,
 	}
 
 	for _, req := range analyzer.Requires {
-		if req == inspect.Analyzer {
-			pass.ResultOf[inspect.Analyzer] = inspector.New(pass.Files)
+		if result, ok := memo[req]; ok {
+			pass.ResultOf[req] = result
 			continue
 		}
-		t.Fatalf("unsupported dependency %q for analyzer %s", req.Name, analyzer.Name)
+		if req == inspect.Analyzer {
+			memo[req] = inspector.New(files)
+		} else {
+			_, memo[req] = runAnalyzerGraph(t, req, fset, files, info, pkg, memo)
+		}
+		pass.ResultOf[req] = memo[req]
 	}
 
 	if analyzer.Run == nil {
 		t.Fatalf("analyzer %s has no Run function", analyzer.Name)
 	}
-	if _, err := analyzer.Run(&pass); err != nil {
+	result, err := analyzer.Run(&pass)
+	if err != nil {
 		t.Fatalf("analyzer %s failed: %v", analyzer.Name, err)
 	}
-	return diags
+	return diags, result
+}
+
+type testFactKey struct {
+	obj types.Object
+	typ reflect.Type
+}
+
+// testFacts holds object facts across runAnalyzerGraph calls. Objects are
+// unique to the type-check that created them, so tests cannot observe each
+// other's facts, while a package checked against an earlier package's types
+// sees the facts exported for it.
+var testFacts sync.Map // testFactKey -> analysis.Fact
+
+func importTestFact(obj types.Object, fact analysis.Fact) bool {
+	stored, ok := testFacts.Load(testFactKey{obj, reflect.TypeOf(fact)})
+	if !ok {
+		return false
+	}
+	reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(stored).Elem())
+	return true
+}
+
+func exportTestFact(obj types.Object, fact analysis.Fact) {
+	testFacts.Store(testFactKey{obj, reflect.TypeOf(fact)}, fact)
+}
+
+// setAnalyzerFlag overrides an analyzer flag for the duration of the test, the
+// same way `go vet -<analyzer>.<flag>=<value>` would.
+func setAnalyzerFlag(t *testing.T, analyzer *analysis.Analyzer, name, value string) {
+	t.Helper()
+
+	flag := analyzer.Flags.Lookup(name)
+	if flag == nil {
+		t.Fatalf("analyzer %s has no flag %q", analyzer.Name, name)
+	}
+	previous := flag.Value.String()
+	if err := analyzer.Flags.Set(name, value); err != nil {
+		t.Fatalf("set %s.%s: %v", analyzer.Name, name, err)
+	}
+	t.Cleanup(func() {
+		_ = analyzer.Flags.Set(name, previous)
+	})
 }
 
 func extractRuleID(message string) string {
@@ -141,6 +232,19 @@ func extractRuleID(message string) string {
 func runAnalyzerOnSource(t *testing.T, analyzer *analysis.Analyzer, filename, src string) []analysis.Diagnostic {
 	t.Helper()
 
+	_, diags := runAnalyzerOnSourceWithFileSet(t, analyzer, filename, src)
+	return diags
+}
+
+// runAnalyzerOnSourceWithFileSet is runAnalyzerOnSource for tests that apply
+// suggested fixes and so need the file set positions refer to.
+func runAnalyzerOnSourceWithFileSet(
+	t *testing.T,
+	analyzer *analysis.Analyzer,
+	filename, src string,
+) (*token.FileSet, []analysis.Diagnostic) {
+	t.Helper()
+
 	fset := token.NewFileSet()
 	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
 	if err != nil {
@@ -161,35 +265,57 @@ func runAnalyzerOnSource(t *testing.T, analyzer *analysis.Analyzer, filename, sr
 		t.Fatalf("type-check %s: %v", filename, err)
 	}
 
-	pass := analysis.Pass{
-		Analyzer:   analyzer,
-		Fset:       fset,
-		Files:      []*ast.File{file},
-		Pkg:        pkg,
-		TypesInfo:  info,
-		TypesSizes: types.SizesFor("gc", runtime.GOARCH),
-		ResultOf:   make(map[*analysis.Analyzer]any),
-	}
+	diags, _ := runAnalyzerGraph(t, analyzer, fset, []*ast.File{file}, info, pkg, nil)
+	return fset, diags
+}
 
-	var diags []analysis.Diagnostic
-	pass.Report = func(d analysis.Diagnostic) {
-		diags = append(diags, d)
-	}
+// applyAllSuggestedFixes applies the first suggested fix of every diagnostic
+// to src at once, as the -fix driver does, merging identical edits, and
+// returns the rewritten source.
+func applyAllSuggestedFixes(t *testing.T, fset *token.FileSet, src string, diags []analysis.Diagnostic) string {
+	t.Helper()
 
-	for _, req := range analyzer.Requires {
-		if req == inspect.Analyzer {
-			pass.ResultOf[inspect.Analyzer] = inspector.New(pass.Files)
-			continue
+	var edits []analysis.TextEdit
+	for _, diag := range diags {
+		if len(diag.SuggestedFixes) == 0 {
+			t.Fatalf("diagnostic %q has no suggested fixes", diag.Message)
+		}
+		for _, edit := range diag.SuggestedFixes[0].TextEdits {
+			if !slices.ContainsFunc(edits, func(other analysis.TextEdit) bool {
+				return other.Pos == edit.Pos && other.End == edit.End && bytes.Equal(other.NewText, edit.NewText)
+			}) {
+				edits = append(edits, edit)
+			}
 		}
-		t.Fatalf("unsupported dependency %q for analyzer %s", req.Name, analyzer.Name)
 	}
+	return applySuggestedFix(t, fset, src, analysis.Diagnostic{
+		SuggestedFixes: []analysis.SuggestedFix{{TextEdits: edits}},
+	})
+}
 
-	if analyzer.Run == nil {
-		t.Fatalf("analyzer %s has no Run function", analyzer.Name)
-	}
-	if _, err := analyzer.Run(&pass); err != nil {
-		t.Fatalf("analyzer %s failed: %v", analyzer.Name, err)
+// applySuggestedFix applies the first suggested fix of diag to src and returns
+// the rewritten source.
+func applySuggestedFix(t *testing.T, fset *token.FileSet, src string, diag analysis.Diagnostic) string {
+	t.Helper()
+
+	if len(diag.SuggestedFixes) == 0 {
+		t.Fatalf("diagnostic %q has no suggested fixes", diag.Message)
 	}
+	edits := append([]analysis.TextEdit(nil), diag.SuggestedFixes[0].TextEdits...)
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Pos > edits[j].Pos })
 
-	return diags
+	out := src
+	for _, edit := range edits {
+		file := fset.File(edit.Pos)
+		if file == nil {
+			t.Fatalf("edit position %d outside of file set", edit.Pos)
+		}
+		start := file.Offset(edit.Pos)
+		end := start
+		if edit.End.IsValid() {
+			end = file.Offset(edit.End)
+		}
+		out = out[:start] + string(edit.NewText) + out[end:]
+	}
+	return out
 }
@@ -0,0 +1,293 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+var httpClientAnalyzer = &analysis.Analyzer{
+	Name:     "perf_reuse_http_client",
+	Doc:      "reports http.Transport and http.Client values with their own transport built per call or per iteration",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_reuse_http_client")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_reuse_http_client not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		reach := handlerReach(pass)
+		covered := make(map[ast.Node]bool)
+		nodeFilter := []ast.Node{(*ast.CompositeLit)(nil), (*ast.CallExpr)(nil)}
+		ins.WithStack(nodeFilter, func(node ast.Node, push bool, stack []ast.Node) bool {
+			expr, _ := node.(ast.Expr)
+			if !push || covered[expr] {
+				return true
+			}
+			what := ""
+			switch {
+			case buildsTransport(pass, expr):
+				what = "http.Transport"
+			case isHTTPType(pass.TypesInfo.TypeOf(expr), "Client"):
+				transport := clientTransport(pass, expr)
+				if transport == nil {
+					return true
+				}
+				covered[transport] = true
+				what = "http.Client with its own http.Transport"
+			default:
+				return true
+			}
+
+			const cost = "discarding pooled connections so every request repeats the TCP and TLS handshakes; " +
+				"share a package-level client or inject one"
+			if innermostLoop(stack) != nil && !keptPerIteration(pass, stack, reach) {
+				report(pass, expr.Pos(), rule, fmt.Sprintf("%s is constructed on every iteration, %s", what, cost))
+				return true
+			}
+			if fn := perCallFunc(pass, stack, reach); fn != "" {
+				report(pass, expr.Pos(), rule, fmt.Sprintf("%s is constructed on every call to %s, %s", what, fn, cost))
+			}
+			return true
+		})
+
+		return nil, nil
+	},
+}
+
+// isHTTPType reports whether typ is the named net/http type name.
+func isHTTPType(typ types.Type, name string) bool {
+	named, ok := types.Unalias(typ).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "net/http" && named.Obj().Name() == name
+}
+
+// buildsTransport reports whether expr creates a new http.Transport: a
+// composite literal, new(http.Transport), or a Clone of an existing one.
+func buildsTransport(pass *analysis.Pass, expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		return isHTTPType(pass.TypesInfo.TypeOf(e), "Transport")
+	case *ast.CallExpr:
+		if tv := pass.TypesInfo.Types[e.Fun]; tv.IsBuiltin() {
+			ident, ok := ast.Unparen(e.Fun).(*ast.Ident)
+			return ok && ident.Name == "new" && len(e.Args) == 1 &&
+				isHTTPType(pass.TypesInfo.TypeOf(e.Args[0]), "Transport")
+		}
+		fn, ok := typeutil.Callee(pass.TypesInfo, e).(*types.Func)
+		if !ok || fn.Name() != "Clone" {
+			return false
+		}
+		recv := fn.Signature().Recv()
+		if recv == nil {
+			return false
+		}
+		ptr, ok := recv.Type().(*types.Pointer)
+		return ok && isHTTPType(ptr.Elem(), "Transport")
+	}
+	return false
+}
+
+// clientTransport returns the transport built inline in the Transport field of
+// an http.Client literal, or nil when the client uses the default transport or
+// one created elsewhere.
+func clientTransport(pass *analysis.Pass, expr ast.Expr) ast.Expr {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if key, ok := kv.Key.(*ast.Ident); !ok || key.Name != "Transport" {
+			continue
+		}
+		value := ast.Unparen(kv.Value)
+		if unary, ok := value.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			value = ast.Unparen(unary.X)
+		}
+		if buildsTransport(pass, value) {
+			return value
+		}
+	}
+	return nil
+}
+
+// perCallFunc returns the name of the function whose every call constructs
+// the innermost node of stack, or "" when it is not known to run per request.
+// That covers HTTP handlers, handler closures, and the package functions reach
+// maps to a handler that calls them, but not closures passed to sync.Once.Do
+// or sync.OnceFunc and its variants.
+func perCallFunc(pass *analysis.Pass, stack []ast.Node, reach map[*types.Func]string) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch fn := stack[i].(type) {
+		case *ast.FuncLit:
+			if isHTTPHandlerSig(pass.TypesInfo.TypeOf(fn)) {
+				return "the handler"
+			}
+			if i > 0 && runsOnce(pass, stack[i-1], fn) {
+				return ""
+			}
+		case *ast.FuncDecl:
+			name := fn.Name.Name
+			if isHandlerDecl(pass, fn) {
+				return name
+			}
+			obj, _ := pass.TypesInfo.Defs[fn.Name].(*types.Func)
+			if handler, ok := reach[obj]; ok {
+				return fmt.Sprintf("%s (reached from %s)", name, handler)
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// isHandlerDecl reports whether fn is a ServeHTTP method or has the signature
+// of an http.HandlerFunc.
+func isHandlerDecl(pass *analysis.Pass, fn *ast.FuncDecl) bool {
+	return (fn.Recv != nil && fn.Name.Name == "ServeHTTP") || isHTTPHandlerSig(pass.TypesInfo.TypeOf(fn.Name))
+}
+
+// handlerReach maps each function of the package that HTTP handlers call,
+// directly or through other functions of the package, to a description of a
+// handler that reaches it. Calls through interfaces or function values are
+// not followed.
+func handlerReach(pass *analysis.Pass) map[*types.Func]string {
+	callees := func(body ast.Node) []*types.Func {
+		var fns []*types.Func
+		ast.Inspect(body, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				if fn := typeutil.StaticCallee(pass.TypesInfo, call); fn != nil && fn.Pkg() == pass.Pkg {
+					fns = append(fns, fn.Origin())
+				}
+			}
+			return true
+		})
+		return fns
+	}
+
+	type visit struct {
+		fn      *types.Func
+		handler string
+	}
+	var queue []visit
+	bodies := make(map[*types.Func]*ast.BlockStmt)
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				fn, ok := pass.TypesInfo.Defs[n.Name].(*types.Func)
+				if !ok || n.Body == nil {
+					return false
+				}
+				bodies[fn] = n.Body
+				if isHandlerDecl(pass, n) {
+					for _, callee := range callees(n.Body) {
+						queue = append(queue, visit{callee, "handler " + n.Name.Name})
+					}
+				}
+			case *ast.FuncLit:
+				if isHTTPHandlerSig(pass.TypesInfo.TypeOf(n)) {
+					for _, callee := range callees(n.Body) {
+						queue = append(queue, visit{callee, "a handler closure"})
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	reach := make(map[*types.Func]string)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if _, seen := reach[next.fn]; seen {
+			continue
+		}
+		body, ok := bodies[next.fn]
+		if !ok {
+			continue
+		}
+		reach[next.fn] = next.handler
+		for _, callee := range callees(body) {
+			queue = append(queue, visit{callee, next.handler})
+		}
+	}
+	return reach
+}
+
+// isHTTPHandlerSig reports whether typ is func(http.ResponseWriter, *http.Request).
+func isHTTPHandlerSig(typ types.Type) bool {
+	sig, ok := typ.(*types.Signature)
+	if !ok || sig.Params().Len() != 2 {
+		return false
+	}
+	req, ok := sig.Params().At(1).Type().(*types.Pointer)
+	return ok && isHTTPType(sig.Params().At(0).Type(), "ResponseWriter") && isHTTPType(req.Elem(), "Request")
+}
+
+// runsOnce reports whether parent calls sync.Once.Do, sync.OnceFunc,
+// sync.OnceValue, or sync.OnceValues with fn, which runs fn at most once.
+func runsOnce(pass *analysis.Pass, parent ast.Node, fn *ast.FuncLit) bool {
+	call, ok := parent.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 || call.Args[0] != fn {
+		return false
+	}
+	callee, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || callee.Pkg() == nil || callee.Pkg().Path() != "sync" {
+		return false
+	}
+	switch callee.Name() {
+	case "Do":
+		return callee.Signature().Recv() != nil
+	case "OnceFunc", "OnceValue", "OnceValues":
+		return true
+	}
+	return false
+}
+
+// keptPerIteration reports whether the innermost node of stack is stored into
+// a map, slice element, or appended outside per-request code, such as
+// building one client per configured backend at startup.
+func keptPerIteration(pass *analysis.Pass, stack []ast.Node, reach map[*types.Func]string) bool {
+	if perCallFunc(pass, stack, reach) != "" {
+		return false
+	}
+	for i := len(stack) - 2; i >= 0; i-- {
+		switch parent := stack[i].(type) {
+		case *ast.UnaryExpr, *ast.ParenExpr, *ast.KeyValueExpr, *ast.CompositeLit:
+			continue
+		case *ast.CallExpr:
+			ident, ok := ast.Unparen(parent.Fun).(*ast.Ident)
+			if ok && ident.Name == "append" && pass.TypesInfo.Types[parent.Fun].IsBuiltin() {
+				return true
+			}
+			return false
+		case *ast.AssignStmt:
+			for _, lhs := range parent.Lhs {
+				if _, ok := ast.Unparen(lhs).(*ast.IndexExpr); ok {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
+	}
+	return false
+}
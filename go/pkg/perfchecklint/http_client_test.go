@@ -0,0 +1,147 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientReportsPerCallConstruction(t *testing.T) {
+	src := `package sample
+
+import (
+	"net/http"
+	"time"
+)
+
+type proxy struct{ base *http.Transport }
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := &http.Client{Transport: p.base.Clone(), Timeout: time.Second}
+	_, _ = client.Do(r)
+	_ = p.forward(r.URL.String())
+}
+
+func (p *proxy) forward(url string) error { return fetch(url) }
+
+func fetch(url string) error {
+	tr := &http.Transport{MaxIdleConns: 10}
+	_, err := (&http.Client{Transport: tr}).Get(url)
+	return err
+}
+
+func pollAll(urls []string) {
+	for _, url := range urls {
+		tr := new(http.Transport)
+		_, _ = (&http.Client{Transport: tr}).Get(url)
+	}
+}
+
+func register(mux *http.ServeMux) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = (&http.Client{Transport: &http.Transport{}}).Get("http://upstream")
+	})
+	mux.HandleFunc("/news", newsHandler)
+}
+
+func newsHandler(w http.ResponseWriter, r *http.Request) {
+	_, _ = (&http.Client{Transport: new(http.Transport)}).Get("http://news")
+}
+`
+
+	diags := runAnalyzerOnSource(t, httpClientAnalyzer, "proxy.go", src)
+	require.Len(t, diags, 5)
+	require.Contains(t, diags[0].Message, "[perf_reuse_http_client] http.Client with its own http.Transport is "+
+		"constructed on every call to ServeHTTP, discarding pooled connections so every request repeats the TCP "+
+		"and TLS handshakes; share a package-level client or inject one")
+	require.Contains(t, diags[1].Message, "http.Transport is constructed on every call to fetch "+
+		"(reached from handler ServeHTTP)")
+	require.Contains(t, diags[2].Message, "http.Transport is constructed on every iteration")
+	require.Contains(t, diags[3].Message, "http.Client with its own http.Transport is constructed on every call "+
+		"to the handler")
+	require.Contains(t, diags[4].Message, "constructed on every call to newsHandler")
+}
+
+func TestHTTPClientIgnoresSetupCodeAndSharedTransports(t *testing.T) {
+	src := `package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+var shared = &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 32}}
+
+var lazy = sync.OnceValue(func() *http.Client {
+	return &http.Client{Transport: &http.Transport{}}
+})
+
+type service struct {
+	client   *http.Client
+	backends map[string]*http.Client
+}
+
+func NewService(hosts []string) *service {
+	s := &service{
+		client:   &http.Client{Transport: &http.Transport{}},
+		backends: make(map[string]*http.Client),
+	}
+	for _, host := range hosts {
+		s.backends[host] = &http.Client{Transport: &http.Transport{}}
+	}
+	return s
+}
+
+func (s *service) get(url string) error {
+	client := &http.Client{Timeout: time.Second}
+	_, err := client.Get(url)
+	if err != nil {
+		_, err = (&http.Client{Transport: shared.Transport}).Get(url)
+	}
+	return err
+}
+
+func init() {
+	http.DefaultClient = &http.Client{Transport: &http.Transport{}}
+}
+
+func buildClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &http.Transport{}, Timeout: timeout}
+}
+
+func setupHTTP() {
+	http.DefaultTransport = &http.Transport{}
+}
+
+func main() {
+	tr := &http.Transport{}
+	_ = &http.Client{Transport: tr}
+	setupHTTP()
+	_ = buildClient(time.Second)
+}
+`
+
+	diags := runAnalyzerOnSource(t, httpClientAnalyzer, "main.go", src)
+	require.Empty(t, diags)
+}
+
+func TestHTTPClientReportsLoopsInSetupCode(t *testing.T) {
+	src := `package main
+
+import "net/http"
+
+func main() {
+	for {
+		client := &http.Client{Transport: &http.Transport{}}
+		if _, err := client.Get("http://localhost/health"); err != nil {
+			return
+		}
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, httpClientAnalyzer, "main.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "http.Client with its own http.Transport is constructed on every iteration")
+}
@@ -0,0 +1,243 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+var interfaceBoxingAnalyzer = &analysis.Analyzer{
+	Name:     "perf_avoid_interface_boxing",
+	Doc:      "reports concrete values implicitly converted to interfaces inside loops",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_avoid_interface_boxing")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_avoid_interface_boxing not found")
+		}
+
+		if pass.TypesSizes == nil {
+			return nil, fmt.Errorf("type sizes unavailable")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		nodeFilter := []ast.Node{
+			(*ast.CallExpr)(nil),
+			(*ast.AssignStmt)(nil),
+			(*ast.ValueSpec)(nil),
+			(*ast.CompositeLit)(nil),
+			(*ast.SendStmt)(nil),
+		}
+		ins.WithStack(nodeFilter, func(node ast.Node, push bool, stack []ast.Node) bool {
+			if !push || !insideLoopBody(stack) {
+				return true
+			}
+			for _, site := range conversionSites(pass, node) {
+				src, size, ok := boxedValue(pass, site.expr, site.target)
+				if !ok {
+					continue
+				}
+				report(pass, site.expr.Pos(), rule, fmt.Sprintf(
+					"%s boxes %s (%d bytes) into %s on every iteration, allocating each time; "+
+						"keep the concrete type inside the loop or convert once outside it",
+					types.ExprString(site.expr), types.TypeString(src, types.RelativeTo(pass.Pkg)), size,
+					types.TypeString(site.target, types.RelativeTo(pass.Pkg))))
+			}
+			return true
+		})
+
+		return nil, nil
+	},
+}
+
+// conversionSite is an expression whose value is assigned to target,
+// implicitly converting it when target is an interface.
+type conversionSite struct {
+	expr   ast.Expr
+	target types.Type
+}
+
+// conversionSites lists the assignability sites of node: call arguments,
+// including variadic ones and the values passed to append, assignments,
+// typed variable declarations, composite literal elements, and channel sends.
+func conversionSites(pass *analysis.Pass, node ast.Node) []conversionSite {
+	var sites []conversionSite
+	switch n := node.(type) {
+	case *ast.CallExpr:
+		sites = callSites(pass, n)
+	case *ast.AssignStmt:
+		if n.Tok != token.ASSIGN || len(n.Lhs) != len(n.Rhs) {
+			break
+		}
+		for i, lhs := range n.Lhs {
+			sites = append(sites, conversionSite{expr: n.Rhs[i], target: pass.TypesInfo.TypeOf(lhs)})
+		}
+	case *ast.ValueSpec:
+		if n.Type == nil || len(n.Names) != len(n.Values) {
+			break
+		}
+		target := pass.TypesInfo.TypeOf(n.Type)
+		for _, value := range n.Values {
+			sites = append(sites, conversionSite{expr: value, target: target})
+		}
+	case *ast.CompositeLit:
+		sites = compositeSites(pass, n)
+	case *ast.SendStmt:
+		if ch, ok := typeUnderlying(pass.TypesInfo.TypeOf(n.Chan)).(*types.Chan); ok {
+			sites = append(sites, conversionSite{expr: n.Value, target: ch.Elem()})
+		}
+	}
+	return sites
+}
+
+// callSites pairs each argument of call with the parameter it is passed to.
+// Conversions and builtins other than append take no implicit conversions.
+func callSites(pass *analysis.Pass, call *ast.CallExpr) []conversionSite {
+	tv := pass.TypesInfo.Types[call.Fun]
+	if tv.IsType() {
+		return nil
+	}
+	if tv.IsBuiltin() {
+		ident, ok := ast.Unparen(call.Fun).(*ast.Ident)
+		if !ok || ident.Name != "append" || len(call.Args) < 2 || call.Ellipsis.IsValid() {
+			return nil
+		}
+		slice, ok := typeUnderlying(pass.TypesInfo.TypeOf(call.Args[0])).(*types.Slice)
+		if !ok {
+			return nil
+		}
+		sites := make([]conversionSite, 0, len(call.Args)-1)
+		for _, arg := range call.Args[1:] {
+			sites = append(sites, conversionSite{expr: arg, target: slice.Elem()})
+		}
+		return sites
+	}
+
+	sig, ok := typeUnderlying(tv.Type).(*types.Signature)
+	if !ok {
+		return nil
+	}
+	params := sig.Params()
+	var sites []conversionSite
+	for i, arg := range call.Args {
+		var target types.Type
+		switch {
+		case sig.Variadic() && i >= params.Len()-1:
+			if call.Ellipsis.IsValid() {
+				return sites
+			}
+			if slice, ok := params.At(params.Len() - 1).Type().(*types.Slice); ok {
+				target = slice.Elem()
+			}
+		case i < params.Len():
+			target = params.At(i).Type()
+		}
+		sites = append(sites, conversionSite{expr: arg, target: target})
+	}
+	return sites
+}
+
+// compositeSites pairs the elements of lit, and the keys of a map literal,
+// with the type they are stored as.
+func compositeSites(pass *analysis.Pass, lit *ast.CompositeLit) []conversionSite {
+	var sites []conversionSite
+	switch t := typeUnderlying(pass.TypesInfo.TypeOf(lit)).(type) {
+	case *types.Slice:
+		sites = elementSites(lit, t.Elem())
+	case *types.Array:
+		sites = elementSites(lit, t.Elem())
+	case *types.Map:
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				sites = append(sites,
+					conversionSite{expr: kv.Key, target: t.Key()},
+					conversionSite{expr: kv.Value, target: t.Elem()})
+			}
+		}
+	case *types.Struct:
+		for i, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				if i < t.NumFields() {
+					sites = append(sites, conversionSite{expr: elt, target: t.Field(i).Type()})
+				}
+				continue
+			}
+			if key, ok := kv.Key.(*ast.Ident); ok {
+				if field, ok := pass.TypesInfo.Uses[key].(*types.Var); ok {
+					sites = append(sites, conversionSite{expr: kv.Value, target: field.Type()})
+				}
+			}
+		}
+	}
+	return sites
+}
+
+// elementSites pairs the elements of a slice or array literal with elem.
+func elementSites(lit *ast.CompositeLit, elem types.Type) []conversionSite {
+	sites := make([]conversionSite, 0, len(lit.Elts))
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = kv.Value
+		}
+		sites = append(sites, conversionSite{expr: elt, target: elem})
+	}
+	return sites
+}
+
+// boxedValue reports whether assigning expr to target allocates an interface
+// box, returning the boxed type and its size. Constants are boxed from
+// read-only data, and pointer-shaped values, zero-size values, and single
+// bytes fit in the interface without allocating.
+func boxedValue(pass *analysis.Pass, expr ast.Expr, target types.Type) (types.Type, int64, bool) {
+	if target == nil || !types.IsInterface(target) {
+		return nil, 0, false
+	}
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value != nil || tv.IsNil() || tv.Type == nil || types.IsInterface(tv.Type) {
+		return nil, 0, false
+	}
+	if pointerShaped(tv.Type) {
+		return nil, 0, false
+	}
+	size := typeSize(pass, tv.Type)
+	if size <= 1 {
+		return nil, 0, false
+	}
+	return tv.Type, size, true
+}
+
+// pointerShaped reports whether values of typ are stored directly in an
+// interface's data word: pointers, maps, channels, functions, and structs or
+// arrays wrapping exactly one of them.
+func pointerShaped(typ types.Type) bool {
+	switch t := typ.Underlying().(type) {
+	case *types.Pointer, *types.Map, *types.Chan, *types.Signature:
+		return true
+	case *types.Basic:
+		return t.Kind() == types.UnsafePointer
+	case *types.Struct:
+		return t.NumFields() == 1 && pointerShaped(t.Field(0).Type())
+	case *types.Array:
+		return t.Len() == 1 && pointerShaped(t.Elem())
+	}
+	return false
+}
+
+func typeUnderlying(typ types.Type) types.Type {
+	if typ == nil {
+		return nil
+	}
+	return typ.Underlying()
+}
@@ -0,0 +1,111 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterfaceBoxingReportsConversionsInLoops(t *testing.T) {
+	src := `package sample
+
+type event struct {
+	id   int64
+	kind string
+}
+
+type logger interface {
+	Log(args ...any)
+}
+
+func record(log logger, events []event, sink chan<- any) map[string]any {
+	fields := make(map[string]any)
+	var args []any
+	for i, ev := range events {
+		args = append(args, ev.id)
+		log.Log("event", ev.kind, i)
+		fields[ev.kind] = ev
+		sink <- ev.id
+		var last any = ev
+		_ = []any{i}
+		_ = last
+	}
+	return fields
+}
+`
+
+	diags := runAnalyzerOnSource(t, interfaceBoxingAnalyzer, "record.go", src)
+	require.Len(t, diags, 7)
+	require.Contains(t, diags[0].Message, "[perf_avoid_interface_boxing] ev.id boxes int64 (8 bytes) into any on "+
+		"every iteration, allocating each time")
+	require.Contains(t, diags[1].Message, "ev.kind boxes string (16 bytes) into any")
+	require.Contains(t, diags[2].Message, "i boxes int (8 bytes) into any")
+	require.Contains(t, diags[3].Message, "ev boxes event (24 bytes) into any")
+	require.Contains(t, diags[4].Message, "ev.id boxes int64 (8 bytes) into any")
+	require.Contains(t, diags[5].Message, "ev boxes event (24 bytes) into any")
+	require.Contains(t, diags[6].Message, "i boxes int (8 bytes) into any")
+}
+
+func TestInterfaceBoxingNamesTheTargetInterface(t *testing.T) {
+	src := `package sample
+
+import "fmt"
+
+type celsius float64
+
+func (c celsius) String() string { return fmt.Sprint(float64(c)) }
+
+type reading struct {
+	label fmt.Stringer
+}
+
+func readings(temps []celsius) []reading {
+	out := make([]reading, 0, len(temps))
+	for _, c := range temps {
+		out = append(out, reading{label: c})
+	}
+	return out
+}
+`
+
+	diags := runAnalyzerOnSource(t, interfaceBoxingAnalyzer, "readings.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "c boxes celsius (8 bytes) into fmt.Stringer")
+}
+
+func TestInterfaceBoxingIgnoresNonAllocatingConversions(t *testing.T) {
+	src := `package sample
+
+import (
+	"errors"
+	"fmt"
+)
+
+type node struct{ next *node }
+
+type handle struct{ p *node }
+
+func walk(nodes []*node, handles []handle, flags []bool, err error, values []any) []any {
+	var out []any
+	for i, n := range nodes {
+		out = append(out, n, handles[i], flags[i], struct{}{}, "const", 42, nil, err, values[i])
+		out = append(out, values...)
+		out = append(out, func() any { return i }())
+	}
+	fmt.Println(len(out))
+	if err != nil {
+		return []any{errors.New("walk"), len(nodes)}
+	}
+	return out
+}
+
+func generic[T any](items []T, sink func(any)) {
+	for _, item := range items {
+		sink(item)
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, interfaceBoxingAnalyzer, "walk.go", src)
+	require.Empty(t, diags)
+}
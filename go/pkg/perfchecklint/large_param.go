@@ -0,0 +1,131 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// largeParamMaxSize is the largest struct or array, in bytes, that a
+// parameter or receiver may take by value without being reported.
+var largeParamMaxSize = ruleIntParam("perf_large_param_copy", "max_size", 128)
+
+func init() {
+	largeParamAnalyzer.Flags.IntVar(&largeParamMaxSize, "max_size", largeParamMaxSize,
+		"largest struct or array size in bytes a parameter or receiver may take by value")
+}
+
+var largeParamAnalyzer = &analysis.Analyzer{
+	Name:     "perf_large_param_copy",
+	Doc:      "reports large structs and arrays passed by value to functions and methods",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_large_param_copy")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_large_param_copy not found")
+		}
+
+		if largeParamMaxSize <= 0 {
+			return nil, fmt.Errorf("max_size must be positive, got %d", largeParamMaxSize)
+		}
+
+		if pass.TypesSizes == nil {
+			return nil, fmt.Errorf("type sizes unavailable")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		ins.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(node ast.Node) {
+			fn, _ := node.(*ast.FuncDecl)
+			if fn.Body == nil {
+				return
+			}
+			for _, param := range largeValueParams(pass, fn) {
+				typ := types.TypeString(param.typ, types.RelativeTo(pass.Pkg))
+				var detail string
+				if param.receiver {
+					detail = fmt.Sprintf("receiver %s copies %d bytes of %s on every call to %s; "+
+						"use a pointer receiver *%s", param.ident.Name, param.size, typ, fn.Name.Name, typ)
+				} else {
+					detail = fmt.Sprintf("parameter %s copies %d bytes of %s on every call to %s; pass *%s instead",
+						param.ident.Name, param.size, typ, fn.Name.Name, typ)
+				}
+				report(pass, param.ident.Pos(), rule, detail)
+			}
+		})
+
+		return nil, nil
+	},
+}
+
+// largeParam is a receiver or parameter that copies more than
+// largeParamMaxSize bytes per call.
+type largeParam struct {
+	ident    *ast.Ident
+	typ      types.Type
+	size     int64
+	receiver bool
+}
+
+// largeValueParams returns the named receiver and parameters of fn that take
+// a large struct or array by value and that fn only reads. Values the body
+// assigns to, takes the address of, or captures in a closure rely on getting
+// a private copy and are left alone.
+func largeValueParams(pass *analysis.Pass, fn *ast.FuncDecl) []largeParam {
+	var candidates []largeParam
+	collect := func(fields *ast.FieldList, receiver bool) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				v, ok := pass.TypesInfo.Defs[name].(*types.Var)
+				if !ok || name.Name == "_" || !isCompositeValue(v.Type()) {
+					continue
+				}
+				if size := typeSize(pass, v.Type()); size > int64(largeParamMaxSize) {
+					candidates = append(candidates, largeParam{ident: name, typ: v.Type(), size: size, receiver: receiver})
+				}
+			}
+		}
+	}
+	collect(fn.Recv, true)
+	collect(fn.Type.Params, false)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	copied := make(map[types.Object]bool)
+	inspectCopyUses(pass, fn.Body, func(ident *ast.Ident, use copyUse) {
+		if use != copyRead {
+			if obj := pass.TypesInfo.Uses[ident]; obj != nil {
+				copied[obj] = true
+			}
+		}
+	})
+	var params []largeParam
+	for _, param := range candidates {
+		if !copied[pass.TypesInfo.Defs[param.ident]] {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// isCompositeValue reports whether typ is a struct or array held by value.
+func isCompositeValue(typ types.Type) bool {
+	switch typ.Underlying().(type) {
+	case *types.Struct, *types.Array:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLargeParamReportsReadOnlyParamsAndReceivers(t *testing.T) {
+	src := `package sample
+
+type config struct {
+	name    string
+	limits  [16]int64
+	weights [8]float64
+}
+
+func (c config) limit(i int) int64 {
+	return c.limits[i]
+}
+
+func describe(c config, table [64]int32, small struct{ a, b int }) string {
+	_ = table[0]
+	_ = small
+	return c.name
+}
+`
+
+	diags := runAnalyzerOnSource(t, largeParamAnalyzer, "config.go", src)
+	require.Len(t, diags, 3)
+	require.Contains(t, diags[0].Message, "[perf_large_param_copy] receiver c copies 208 bytes of config on every "+
+		"call to limit; use a pointer receiver *config")
+	require.Contains(t, diags[1].Message, "parameter c copies 208 bytes of config on every call to describe; "+
+		"pass *config instead")
+	require.Contains(t, diags[2].Message, "parameter table copies 256 bytes of [64]int32 on every call to describe")
+}
+
+func TestLargeParamIgnoresValuesThatRelyOnCopies(t *testing.T) {
+	src := `package sample
+
+type matrix struct {
+	cells [32]float64
+}
+
+func (m *matrix) scale(f float64) {
+	for i := range m.cells {
+		m.cells[i] *= f
+	}
+}
+
+func normalized(m matrix) matrix {
+	m.scale(0.5)
+	return m
+}
+
+func zeroed(m matrix) matrix {
+	m.cells[0] = 0
+	return m
+}
+
+func sorted(m matrix, sortInPlace func([]float64)) matrix {
+	sortInPlace(m.cells[:])
+	return m
+}
+
+func later(m matrix) func() float64 {
+	return func() float64 { return m.cells[0] }
+}
+
+func (m matrix) withFirst(v float64) matrix {
+	m.cells[0] = v
+	return m
+}
+
+func unused(_ matrix, _ [64]int32) {}
+
+func external(m matrix) float64
+`
+
+	diags := runAnalyzerOnSource(t, largeParamAnalyzer, "matrix.go", src)
+	require.Empty(t, diags)
+}
+
+func TestLargeParamSkipsTypeParametersAndHonorsMaxSize(t *testing.T) {
+	setAnalyzerFlag(t, largeParamAnalyzer, "max_size", "16")
+
+	src := `package sample
+
+type pair[T any] struct{ a, b T }
+
+type point struct{ x, y, z float64 }
+
+func first[T any](p pair[T]) T { return p.a }
+
+func length(p point) float64 { return p.x + p.y + p.z }
+`
+
+	diags := runAnalyzerOnSource(t, largeParamAnalyzer, "point.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "parameter p copies 24 bytes of point on every call to length")
+}
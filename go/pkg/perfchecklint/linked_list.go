@@ -14,7 +14,7 @@ import (
 var linkedListAnalyzer = &analysis.Analyzer{
 	Name:     "perf_avoid_linked_list",
 	Doc:      "reports container/list usage",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_avoid_linked_list")
 		if !ok {
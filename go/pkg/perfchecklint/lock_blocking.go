@@ -0,0 +1,242 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+var sqlBlockingMethods = []string{
+	"Begin", "BeginTx", "Commit", "Exec", "ExecContext", "Ping", "PingContext", "Prepare", "PrepareContext",
+	"Query", "QueryContext", "QueryRow", "QueryRowContext", "Rollback",
+}
+
+// blockingCalls lists standard library and golang.org/x/sync calls that park
+// the calling goroutine on the network, the file system, a timer, or other
+// goroutines, keyed by package path and, for methods, the receiver's type
+// name.
+var blockingCalls = map[string][]string{
+	"time":            {"Sleep"},
+	"sync.WaitGroup":  {"Wait"},
+	"net/http":        {"Get", "Head", "Post", "PostForm"},
+	"net/http.Client": {"Do", "Get", "Head", "Post", "PostForm"},
+	"net": {
+		"Dial", "DialIP", "DialTCP", "DialTimeout", "DialUDP", "DialUnix", "LookupHost", "LookupIP",
+	},
+	"net.Dialer":      {"Dial", "DialContext"},
+	"net.Conn":        {"Read", "Write"},
+	"net.conn":        {"Read", "Write"},
+	"net.Listener":    {"Accept"},
+	"net.TCPListener": {"Accept", "AcceptTCP"},
+	"os":              {"ReadDir", "ReadFile", "WriteFile"},
+	"os.File": {
+		"Read", "ReadAt", "ReadDir", "ReadFrom", "Readdir", "Sync", "Write", "WriteAt", "WriteString",
+	},
+	"io":                               {"Copy", "CopyBuffer", "CopyN", "ReadAll", "ReadAtLeast", "ReadFull"},
+	"database/sql.DB":                  sqlBlockingMethods,
+	"database/sql.Tx":                  sqlBlockingMethods,
+	"database/sql.Conn":                sqlBlockingMethods,
+	"database/sql.Stmt":                sqlBlockingMethods,
+	"database/sql.Rows":                {"Next"},
+	"database/sql.Row":                 {"Scan"},
+	"os/exec.Cmd":                      {"CombinedOutput", "Output", "Run", "Wait"},
+	"golang.org/x/sync/errgroup.Group": {"Wait"},
+}
+
+var lockBlockingAnalyzer = &analysis.Analyzer{
+	Name:     "perf_no_blocking_under_lock",
+	Doc:      "reports channel operations, I/O, sleeps, and waits made while a sync mutex is held",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_no_blocking_under_lock")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_no_blocking_under_lock not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		nodeFilter := []ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}
+		ins.Preorder(nodeFilter, func(node ast.Node) {
+			var body *ast.BlockStmt
+			switch fn := node.(type) {
+			case *ast.FuncDecl:
+				body = fn.Body
+			case *ast.FuncLit:
+				body = fn.Body
+			}
+			analyzeLockedSections(pass, body, rule)
+		})
+
+		return nil, nil
+	},
+}
+
+// blockingOp is an operation that can park the goroutine, described for the
+// diagnostic.
+type blockingOp struct {
+	pos  token.Pos
+	what string
+}
+
+// analyzeLockedSections checks every statement list of body, leaving nested
+// function literals to their own pass.
+func analyzeLockedSections(pass *analysis.Pass, body *ast.BlockStmt, rule ruleset.Rule) {
+	if body == nil {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.BlockStmt:
+			checkLockedStmts(pass, n.List, rule)
+		case *ast.CaseClause:
+			checkLockedStmts(pass, n.Body, rule)
+		case *ast.CommClause:
+			checkLockedStmts(pass, n.Body, rule)
+		}
+		return true
+	})
+}
+
+func checkLockedStmts(pass *analysis.Pass, stmts []ast.Stmt, rule ruleset.Rule) {
+	for i, stmt := range stmts {
+		receiver, method, ok := mutexLockCall(pass, stmt)
+		if !ok {
+			continue
+		}
+		line := pass.Fset.Position(stmt.Pos()).Line
+		for _, op := range blockingOps(pass, lockedSection(pass, stmts[i+1:], receiver), receiver) {
+			reportRelated(pass, op.pos, rule, fmt.Sprintf(
+				"%s blocks while %s is held (%s.%s() on line %d); release the lock before blocking",
+				op.what, receiver.expr, receiver.expr, method, line),
+				analysis.RelatedInformation{
+					Pos:     stmt.Pos(),
+					End:     stmt.End(),
+					Message: fmt.Sprintf("%s acquired here", receiver.expr),
+				})
+		}
+	}
+}
+
+// lockedSection returns the statements that run with receiver held: up to
+// the matching Unlock in the same list, or the rest of the list when the
+// unlock is deferred right after locking. It returns nil when the list never
+// releases the lock.
+func lockedSection(pass *analysis.Pass, stmts []ast.Stmt, receiver mutexTarget) []ast.Stmt {
+	if len(stmts) > 0 {
+		if deferStmt, ok := stmts[0].(*ast.DeferStmt); ok &&
+			isUnlockCall(pass, &ast.ExprStmt{X: deferStmt.Call}, receiver) {
+			return stmts[1:]
+		}
+	}
+	for j, stmt := range stmts {
+		if isUnlockCall(pass, stmt, receiver) {
+			return stmts[:j]
+		}
+	}
+	return nil
+}
+
+// blockingOps finds the operations in stmts that can park the goroutine while
+// receiver is held. Function literals, go statements, and deferred calls run
+// at another time and are skipped, as are the cases of a select with a default
+// clause and the rest of a nested block once it unlocks receiver, as in an
+// early return.
+func blockingOps(pass *analysis.Pass, stmts []ast.Stmt, receiver mutexTarget) []blockingOp {
+	var ops []blockingOp
+	skip := make(map[ast.Node]bool)
+	skipAfterUnlock := func(list []ast.Stmt) {
+		for j, stmt := range list {
+			if isUnlockCall(pass, stmt, receiver) {
+				for _, rest := range list[j+1:] {
+					skip[rest] = true
+				}
+				return
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if n == nil || skip[n] {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.FuncLit, *ast.GoStmt, *ast.DeferStmt:
+				return false
+			case *ast.BlockStmt:
+				skipAfterUnlock(n.List)
+			case *ast.CaseClause:
+				skipAfterUnlock(n.Body)
+			case *ast.CommClause:
+				skipAfterUnlock(n.Body)
+			case *ast.SelectStmt:
+				hasDefault := false
+				for _, clause := range n.Body.List {
+					if comm, ok := clause.(*ast.CommClause); ok {
+						if comm.Comm == nil {
+							hasDefault = true
+						} else {
+							skip[comm.Comm] = true
+						}
+					}
+				}
+				if !hasDefault {
+					ops = append(ops, blockingOp{pos: n.Pos(), what: "select without a default case"})
+				}
+			case *ast.SendStmt:
+				ops = append(ops, blockingOp{pos: n.Pos(), what: "channel send"})
+			case *ast.UnaryExpr:
+				if n.Op == token.ARROW {
+					ops = append(ops, blockingOp{pos: n.Pos(), what: "channel receive"})
+				}
+			case *ast.RangeStmt:
+				if typ := pass.TypesInfo.TypeOf(n.X); typ != nil {
+					if _, ok := typ.Underlying().(*types.Chan); ok {
+						ops = append(ops, blockingOp{pos: n.Pos(), what: "range over a channel"})
+					}
+				}
+			case *ast.CallExpr:
+				if isBlockingCall(pass, n) {
+					ops = append(ops, blockingOp{pos: n.Pos(), what: types.ExprString(n.Fun)})
+				}
+			}
+			return true
+		})
+	}
+	return ops
+}
+
+// isBlockingCall reports whether call invokes a function or method listed in
+// blockingCalls, including interface methods such as net.Conn.Read.
+func isBlockingCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return false
+	}
+	key := fn.Pkg().Path()
+	if recv := fn.Signature().Recv(); recv != nil {
+		t := recv.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		named, ok := types.Unalias(t).(*types.Named)
+		if !ok {
+			return false
+		}
+		key += "." + named.Obj().Name()
+	}
+	return slices.Contains(blockingCalls[key], fn.Name())
+}
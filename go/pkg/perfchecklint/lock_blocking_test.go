@@ -0,0 +1,166 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockBlockingReportsBlockingWorkInCriticalSections(t *testing.T) {
+	src := `package sample
+
+import (
+	"database/sql"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type store struct {
+	mu    sync.RWMutex
+	db    *sql.DB
+	conn  net.Conn
+	http  *http.Client
+	queue chan string
+	wg    sync.WaitGroup
+}
+
+func (s *store) refresh(req *http.Request, buf []byte) {
+	s.mu.Lock()
+	time.Sleep(time.Millisecond)
+	resp, _ := s.http.Do(req)
+	_ = resp
+	_, _ = s.db.Query("SELECT 1")
+	_, _ = s.conn.Read(buf)
+	_, _ = os.ReadFile("state.json")
+	s.wg.Wait()
+	s.mu.Unlock()
+}
+
+func (s *store) next() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	select {
+	case v := <-s.queue:
+		return v
+	case <-time.After(time.Second):
+		return ""
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, lockBlockingAnalyzer, "store.go", src)
+	require.Len(t, diags, 7)
+	require.Contains(t, diags[0].Message, "[perf_no_blocking_under_lock] time.Sleep blocks while s.mu is held "+
+		"(s.mu.Lock() on line 22); release the lock before blocking")
+	for i, what := range []string{"s.http.Do", "s.db.Query", "s.conn.Read", "os.ReadFile", "s.wg.Wait"} {
+		require.Contains(t, diags[i+1].Message, what+" blocks while s.mu is held")
+	}
+	require.Contains(t, diags[6].Message, "select without a default case blocks while s.mu is held "+
+		"(s.mu.RLock() on line 34)")
+	require.Len(t, diags[6].Related, 1)
+	require.Equal(t, "s.mu acquired here", diags[6].Related[0].Message)
+}
+
+func TestLockBlockingReportsChannelOperations(t *testing.T) {
+	src := `package sample
+
+import "sync"
+
+var mu sync.Mutex
+
+func relay(in <-chan int, out chan<- int) {
+	mu.Lock()
+	v := <-in
+	out <- v
+	for v := range in {
+		_ = v
+	}
+	mu.Unlock()
+}
+`
+
+	diags := runAnalyzerOnSource(t, lockBlockingAnalyzer, "relay.go", src)
+	require.Len(t, diags, 3)
+	require.Contains(t, diags[0].Message, "channel receive blocks while mu is held")
+	require.Contains(t, diags[1].Message, "channel send blocks while mu is held")
+	require.Contains(t, diags[2].Message, "range over a channel blocks while mu is held")
+}
+
+func TestLockBlockingIgnoresWorkOutsideTheSection(t *testing.T) {
+	src := `package sample
+
+import (
+	"sync"
+	"time"
+)
+
+type cache struct {
+	mu      sync.Mutex
+	items   map[string]string
+	updates chan string
+}
+
+func (c *cache) set(k, v string) {
+	c.mu.Lock()
+	c.items[k] = v
+	select {
+	case c.updates <- k:
+	default:
+	}
+	go func() { c.updates <- k }()
+	defer time.Sleep(0)
+	c.mu.Unlock()
+	c.updates <- k
+	time.Sleep(time.Millisecond)
+}
+
+func (c *cache) unpaired() {
+	c.mu.Lock()
+	time.Sleep(time.Millisecond)
+}
+`
+
+	diags := runAnalyzerOnSource(t, lockBlockingAnalyzer, "cache.go", src)
+	require.Empty(t, diags)
+}
+
+func TestLockBlockingStopsAtEarlyUnlock(t *testing.T) {
+	src := `package sample
+
+import "sync"
+
+type queue struct {
+	mu    sync.Mutex
+	done  bool
+	items []int
+	out   chan int
+}
+
+func (q *queue) push(v int) {
+	q.mu.Lock()
+	if q.done {
+		q.mu.Unlock()
+		q.out <- v
+		return
+	}
+	switch {
+	case v < 0:
+		q.mu.Unlock()
+		q.out <- -v
+		return
+	}
+	q.items = append(q.items, v)
+	if len(q.items) > 8 {
+		q.out <- len(q.items)
+	}
+	q.mu.Unlock()
+}
+`
+
+	diags := runAnalyzerOnSource(t, lockBlockingAnalyzer, "queue.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "channel send blocks while q.mu is held (q.mu.Lock() on line 13)")
+}
@@ -0,0 +1,341 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// hoistableCalls lists pure functions that are expensive enough to hoist out
+// of loops, keyed by "pkgpath.Name", along with the name given to the local
+// that holds a hoisted result.
+var hoistableCalls = map[string]string{
+	"strings.Split":       "parts",
+	"strings.SplitN":      "parts",
+	"strings.SplitAfter":  "parts",
+	"strings.Fields":      "fields",
+	"strings.ToLower":     "lower",
+	"strings.ToUpper":     "upper",
+	"strings.ToTitle":     "title",
+	"strings.Repeat":      "repeated",
+	"strings.Replace":     "replaced",
+	"strings.ReplaceAll":  "replaced",
+	"fmt.Sprintf":         "formatted",
+	"fmt.Sprint":          "formatted",
+	"fmt.Sprintln":        "formatted",
+	"time.LoadLocation":   "loc",
+	"path/filepath.Abs":   "abs",
+	"path/filepath.Join":  "joined",
+	"path/filepath.Clean": "cleaned",
+}
+
+var loopInvariantCallAnalyzer = &analysis.Analyzer{
+	Name:     "perf_hoist_loop_invariant_call",
+	Doc:      "reports expensive pure calls with loop-invariant arguments inside loops",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_hoist_loop_invariant_call")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_hoist_loop_invariant_call not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+			call, _ := node.(*ast.CallExpr)
+			if !push || hoistableCall(pass, call) == nil {
+				return true
+			}
+			if _, ok := stack[len(stack)-2].(*ast.ExprStmt); ok {
+				return true
+			}
+			loop := innermostLoop(stack)
+			if loop == nil {
+				return true
+			}
+			scope := &loopScope{pass: pass, loop: loop, fn: enclosingFunc(enclosingPath(pass, call))}
+			if !scope.invariantCall(call) {
+				return true
+			}
+
+			detail := fmt.Sprintf("%s recomputes the same result on every iteration; hoist it before the loop",
+				funcDisplayName(hoistableCall(pass, call)))
+			// Hoisting runs the call once even when the loop would not, so
+			// the fix is only offered where every iteration runs it anyway.
+			if runsEveryIteration(stack, loop) && !hoistMayPanic(pass, call) {
+				if fix, ok := hoistFix(pass, loop, call); ok {
+					reportWithFixes(pass, call, rule, detail, fix)
+					return false
+				}
+			}
+			report(pass, call.Pos(), rule, detail)
+			// Nested hoistable calls move along with this one.
+			return false
+		})
+
+		return nil, nil
+	},
+}
+
+// hoistableCall returns the function call invokes when it is listed in
+// hoistableCalls, or nil.
+func hoistableCall(pass *analysis.Pass, call *ast.CallExpr) *types.Func {
+	if call == nil || call.Ellipsis.IsValid() {
+		return nil
+	}
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.Pkg() == nil || fn.Signature().Recv() != nil {
+		return nil
+	}
+	if _, ok := hoistableCalls[fn.Pkg().Path()+"."+fn.Name()]; !ok {
+		return nil
+	}
+	return fn
+}
+
+// loopScope decides which expressions evaluate to the same value on every
+// iteration of loop inside the function body fn.
+type loopScope struct {
+	pass *analysis.Pass
+	loop ast.Stmt
+	fn   *ast.BlockStmt
+}
+
+// invariantCall reports whether every argument of a hoistable call is loop
+// invariant.
+func (s *loopScope) invariantCall(call *ast.CallExpr) bool {
+	for _, arg := range call.Args {
+		if !s.invariant(arg) {
+			return false
+		}
+	}
+	return true
+}
+
+// invariant reports whether expr is built from constants, variables the loop
+// never changes, and nested hoistable calls. Only values of plain basic types
+// qualify: fmt could otherwise run String methods with side effects, and
+// slices or pointers could be changed through aliases.
+func (s *loopScope) invariant(expr ast.Expr) bool {
+	tv, ok := s.pass.TypesInfo.Types[expr]
+	if !ok {
+		return false
+	}
+	if tv.Value != nil {
+		return !s.declaredInLoop(expr)
+	}
+	if _, ok := tv.Type.Underlying().(*types.Basic); !ok || types.NewMethodSet(types.NewPointer(tv.Type)).Len() > 0 {
+		return false
+	}
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return s.invariant(e.X)
+	case *ast.BinaryExpr:
+		return s.invariant(e.X) && s.invariant(e.Y)
+	case *ast.UnaryExpr:
+		return e.Op != token.ARROW && s.invariant(e.X)
+	case *ast.Ident:
+		v, ok := s.pass.TypesInfo.Uses[e].(*types.Var)
+		return ok && s.stableVar(v)
+	case *ast.CallExpr:
+		return hoistableCall(s.pass, e) != nil && s.invariantCall(e)
+	}
+	return false
+}
+
+// declaredInLoop reports whether expr names a constant or type declared inside
+// the loop, which would be undefined once the expression is hoisted above it.
+func (s *loopScope) declaredInLoop(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if obj := s.pass.TypesInfo.Uses[ident]; obj != nil && s.loop.Pos() <= obj.Pos() && obj.Pos() < s.loop.End() {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// stableVar reports whether v is a local declared before the loop that
+// neither the loop nor any closure assigns and whose address is never taken.
+func (s *loopScope) stableVar(v *types.Var) bool {
+	if s.fn == nil || v.Pkg() != s.pass.Pkg || v.Parent() == v.Pkg().Scope() || v.Pos() >= s.loop.Pos() {
+		return false
+	}
+	stable := true
+	var closures []*ast.FuncLit
+	assigns := func(n ast.Node, expr ast.Expr) {
+		ident, ok := ast.Unparen(expr).(*ast.Ident)
+		if !ok || s.pass.TypesInfo.ObjectOf(ident) != v {
+			return
+		}
+		inLoop := s.loop.Pos() <= n.Pos() && n.Pos() < s.loop.End()
+		for _, lit := range closures {
+			if lit.Pos() <= n.Pos() && n.Pos() < lit.End() {
+				inLoop = true
+			}
+		}
+		if inLoop {
+			stable = false
+		}
+	}
+	ast.Inspect(s.fn, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			closures = append(closures, n)
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				assigns(n, lhs)
+			}
+		case *ast.IncDecStmt:
+			assigns(n, n.X)
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				assigns(n, n.Key)
+				assigns(n, n.Value)
+			}
+		case *ast.UnaryExpr:
+			if ident, ok := ast.Unparen(n.X).(*ast.Ident); ok && n.Op == token.AND &&
+				s.pass.TypesInfo.Uses[ident] == v {
+				stable = false
+			}
+		}
+		return stable
+	})
+	return stable
+}
+
+// runsEveryIteration reports whether the innermost node of stack is evaluated
+// on every iteration of loop: in its condition, or in a statement of its body
+// that no earlier statement can skip, outside nested branches and the right
+// operand of && and ||.
+func runsEveryIteration(stack []ast.Node, loop ast.Stmt) bool {
+	for i := len(stack) - 2; i >= 0; i-- {
+		child := stack[i+1]
+		switch parent := stack[i].(type) {
+		case *ast.BinaryExpr:
+			if (parent.Op == token.LAND || parent.Op == token.LOR) && child == parent.Y {
+				return false
+			}
+		case *ast.BlockStmt:
+			body := loopBody(loop)
+			if parent != body {
+				return false
+			}
+			stmt, _ := child.(ast.Stmt)
+			return !leavesIteration(body.List[:slices.Index(body.List, stmt)])
+		case *ast.ForStmt:
+			if parent == loop {
+				return child == parent.Cond
+			}
+			if child != parent.Init {
+				return false
+			}
+		case *ast.RangeStmt:
+			if parent == loop || child != parent.X {
+				return false
+			}
+		case *ast.IfStmt:
+			if child != parent.Init && child != parent.Cond {
+				return false
+			}
+		case *ast.SwitchStmt:
+			if child != parent.Init && child != parent.Tag {
+				return false
+			}
+		case *ast.TypeSwitchStmt:
+			if child != parent.Init && child != parent.Assign {
+				return false
+			}
+		case *ast.CaseClause, *ast.CommClause, *ast.SelectStmt, *ast.FuncLit:
+			return false
+		}
+	}
+	return false
+}
+
+// leavesIteration reports whether any of stmts may end the iteration early
+// with a branch, return, or panic.
+func leavesIteration(stmts []ast.Stmt) bool {
+	found := false
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.BranchStmt, *ast.ReturnStmt:
+				found = true
+			case *ast.CallExpr:
+				if ident, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && ident.Name == "panic" {
+					found = true
+				}
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// hoistMayPanic reports whether running call before the loop could panic
+// where the loop body would not: strings.Repeat panics on a negative count.
+func hoistMayPanic(pass *analysis.Pass, call *ast.CallExpr) bool {
+	fn := hoistableCall(pass, call)
+	if fn.Pkg().Path() != "strings" || fn.Name() != "Repeat" || len(call.Args) != 2 {
+		return false
+	}
+	n, ok := constInt(pass, call.Args[1])
+	return !ok || n < 0
+}
+
+// hoistFix moves call into fresh locals declared just before loop and uses
+// them in its place.
+func hoistFix(pass *analysis.Pass, loop ast.Stmt, call *ast.CallExpr) (analysis.SuggestedFix, bool) {
+	path := enclosingPath(pass, loop)
+	fnBody := enclosingFunc(path)
+	if fnBody == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	anchor := statementAnchor(path, loop)
+
+	fn := hoistableCall(pass, call)
+	base := hoistableCalls[fn.Pkg().Path()+"."+fn.Name()]
+	results := fn.Signature().Results()
+	names := make([]string, 0, results.Len())
+	for i := range results.Len() {
+		name := base
+		if types.Identical(results.At(i).Type(), types.Universe.Lookup("error").Type()) {
+			name = "err"
+		}
+		names = append(names, freshName(pass, anchor.Pos(), fnBody, name))
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i] == names[i-1] {
+			return analysis.SuggestedFix{}, false
+		}
+	}
+
+	list := strings.Join(names, ", ")
+	decl := fmt.Sprintf("%s := %s\n%s", list, types.ExprString(call), lineIndent(pass, anchor.Pos()))
+	return analysis.SuggestedFix{
+		Message: fmt.Sprintf("Hoist %s before the loop", funcDisplayName(fn)),
+		TextEdits: []analysis.TextEdit{
+			{Pos: anchor.Pos(), End: anchor.Pos(), NewText: []byte(decl)},
+			{Pos: call.Pos(), End: call.End(), NewText: []byte(list)},
+		},
+	}, true
+}
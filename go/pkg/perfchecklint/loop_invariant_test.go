@@ -0,0 +1,141 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoopInvariantCallHoistsPureCalls(t *testing.T) {
+	src := `package sample
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func stamp(events []time.Time, zone, format string, id int) []string {
+	var out []string
+	for _, ev := range events {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			continue
+		}
+		out = append(out, ev.In(loc).Format(format)+fmt.Sprintf("#%d", id))
+	}
+	for i := 0; i < len(strings.Split(strings.TrimSpace(format), ",")); i++ {
+		out = append(out, strings.ToUpper(strings.ToLower(format)))
+	}
+	return out
+}
+`
+	want := `package sample
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func stamp(events []time.Time, zone, format string, id int) []string {
+	var out []string
+	loc2, err2 := time.LoadLocation(zone)
+	for _, ev := range events {
+		loc, err := loc2, err2
+		if err != nil {
+			continue
+		}
+		out = append(out, ev.In(loc).Format(format)+fmt.Sprintf("#%d", id))
+	}
+	for i := 0; i < len(strings.Split(strings.TrimSpace(format), ",")); i++ {
+		out = append(out, strings.ToUpper(strings.ToLower(format)))
+	}
+	return out
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, loopInvariantCallAnalyzer, "stamp.go", src)
+	require.Len(t, diags, 3)
+	require.Contains(t, diags[0].Message, "[perf_hoist_loop_invariant_call] time.LoadLocation recomputes "+
+		"the same result on every iteration; hoist it before the loop")
+	require.Contains(t, diags[1].Message, "fmt.Sprintf recomputes")
+	require.Contains(t, diags[2].Message, "strings.ToUpper recomputes")
+	require.Equal(t, want, applySuggestedFix(t, fset, src, diags[0]))
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[2]),
+		"\tupper := strings.ToUpper(strings.ToLower(format))\n\tfor i := 0;")
+}
+
+func TestLoopInvariantCallIgnoresVaryingArguments(t *testing.T) {
+	src := `package sample
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type id int
+
+func (i id) String() string { return "id" }
+
+func paths(dirs []string, base string, n id) {
+	sep := ","
+	for _, dir := range dirs {
+		_, _ = filepath.Abs(dir)
+		_ = fmt.Sprint(n)
+		_ = strings.Split(base, sep)
+		sep = ";"
+	}
+	for i := range dirs {
+		strings.ToLower(base)
+		_ = fmt.Sprintf("%d", i)
+		go func() { _ = strings.ToLower(base) }()
+	}
+	lower := strings.ToLower(base)
+	_ = lower
+	for range dirs {
+		const sep = "/"
+		_ = strings.ToUpper(sep)
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, loopInvariantCallAnalyzer, "paths.go", src)
+	require.Empty(t, diags)
+}
+
+func TestLoopInvariantCallOffersFixOnlyForEveryIteration(t *testing.T) {
+	src := `package sample
+
+import "strings"
+
+func pad(items []string, sep string, n int, strict bool) []string {
+	var out []string
+	for _, item := range items {
+		if n >= 0 {
+			out = append(out, item+strings.Repeat("ab", n))
+		}
+		ok := strict && strings.ToLower(sep) == item
+		if item == "" {
+			continue
+		}
+		out = append(out, strings.ToUpper(sep)+strings.Repeat("-", n))
+		_ = ok
+	}
+	for _, item := range items {
+		out = append(out, strings.Repeat("=", 4)+item)
+	}
+	return out
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, loopInvariantCallAnalyzer, "pad.go", src)
+	require.Len(t, diags, 5)
+	for _, diag := range diags[:4] {
+		require.Empty(t, diag.SuggestedFixes, diag.Message)
+	}
+	require.Contains(t, diags[4].Message, "strings.Repeat recomputes")
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[4]),
+		"\trepeated := strings.Repeat(\"=\", 4)\n\tfor _, item := range items {\n\t\tout = append(out, repeated+item)")
+}
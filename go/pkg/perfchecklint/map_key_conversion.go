@@ -0,0 +1,249 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// mapKeyConversionAnalyzer reports string([]byte) conversions that lose the
+// compiler's no-copy map lookup: the conversion only avoids allocating when
+// it appears inline as m[string(b)] in a read, so storing it in a temporary
+// first copies the bytes, and converting the same bytes for map writes on
+// every loop iteration copies them each time.
+var mapKeyConversionAnalyzer = &analysis.Analyzer{
+	Name:     "perf_inline_byte_to_string_mapkey",
+	Doc:      "reports string([]byte) map keys stored in temporaries or rebuilt inside loops",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_inline_byte_to_string_mapkey")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_inline_byte_to_string_mapkey not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		keys := collectMapKeyIdents(pass, ins)
+		var uses map[types.Object][]*ast.Ident
+		ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+			call, _ := node.(*ast.CallExpr)
+			if !push || !isByteStringConversion(pass, call) {
+				return true
+			}
+			conv := types.ExprString(call)
+
+			switch parent := stack[len(stack)-2].(type) {
+			case *ast.IndexExpr:
+				if parent.Index != call || !isMapIndex(pass, parent) || isMapLookup(parent, stack[len(stack)-3]) {
+					return true
+				}
+			default:
+				temp := conversionTemp(pass, parent, call)
+				if temp == nil {
+					return true
+				}
+				if uses == nil {
+					uses = identUses(pass)
+				}
+				lookups, writes := 0, 0
+				for _, ident := range uses[temp] {
+					switch keys[ident] {
+					case mapKeyLookup:
+						lookups++
+					case mapKeyWrite:
+						writes++
+					default:
+						return true
+					}
+				}
+				if lookups > 0 && writes == 0 {
+					report(pass, call.Pos(), rule, fmt.Sprintf(
+						"%s is stored in %s before a map lookup, which copies the bytes; "+
+							"index with m[%s] so the lookup does not allocate", conv, temp.Name(), conv))
+					return true
+				}
+				if writes == 0 {
+					return true
+				}
+			}
+
+			if loop := innermostLoop(stack); loop != nil && loopInvariantBytes(pass, loop, call.Args[0]) {
+				report(pass, call.Pos(), rule, fmt.Sprintf(
+					"%s copies the same bytes into a new map key on every iteration; convert once before the loop",
+					conv))
+			}
+			return true
+		})
+
+		return nil, nil
+	},
+}
+
+// mapKeyUse classifies an identifier used as a map index.
+type mapKeyUse int
+
+const (
+	mapKeyNone mapKeyUse = iota
+	mapKeyLookup
+	mapKeyWrite
+)
+
+// collectMapKeyIdents records every identifier used as the key of a map index
+// expression and whether that index only reads the map.
+func collectMapKeyIdents(pass *analysis.Pass, ins *inspector.Inspector) map[*ast.Ident]mapKeyUse {
+	keys := make(map[*ast.Ident]mapKeyUse)
+	ins.WithStack([]ast.Node{(*ast.IndexExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+		index, _ := node.(*ast.IndexExpr)
+		if !push || !isMapIndex(pass, index) {
+			return true
+		}
+		ident, ok := ast.Unparen(index.Index).(*ast.Ident)
+		if !ok {
+			return true
+		}
+		keys[ident] = mapKeyWrite
+		if isMapLookup(index, stack[len(stack)-2]) {
+			keys[ident] = mapKeyLookup
+		}
+		return true
+	})
+	return keys
+}
+
+func isMapIndex(pass *analysis.Pass, index *ast.IndexExpr) bool {
+	typ := pass.TypesInfo.TypeOf(index.X)
+	if typ == nil {
+		return false
+	}
+	_, ok := typ.Underlying().(*types.Map)
+	return ok
+}
+
+// isMapLookup reports whether the map index expression only reads the map;
+// assignments and increments must store the key and so always copy it.
+func isMapLookup(index *ast.IndexExpr, parent ast.Node) bool {
+	switch parent := parent.(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range parent.Lhs {
+			if lhs == index {
+				return false
+			}
+		}
+	case *ast.IncDecStmt:
+		return false
+	case *ast.RangeStmt:
+		return parent.Key != index && parent.Value != index
+	}
+	return true
+}
+
+// isByteStringConversion reports whether call converts a byte slice to a
+// string.
+func isByteStringConversion(pass *analysis.Pass, call *ast.CallExpr) bool {
+	if call == nil || len(call.Args) != 1 || pass.TypesInfo == nil {
+		return false
+	}
+	tv, ok := pass.TypesInfo.Types[call.Fun]
+	if !ok || !tv.IsType() {
+		return false
+	}
+	if basic, ok := tv.Type.Underlying().(*types.Basic); !ok || basic.Info()&types.IsString == 0 {
+		return false
+	}
+	arg := pass.TypesInfo.TypeOf(call.Args[0])
+	if arg == nil {
+		return false
+	}
+	slice, ok := arg.Underlying().(*types.Slice)
+	if !ok {
+		return false
+	}
+	elem, ok := slice.Elem().Underlying().(*types.Basic)
+	return ok && elem.Kind() == types.Byte
+}
+
+// conversionTemp returns the local variable that parent declares to hold
+// the result of call, as in k := string(b) or var k = string(b).
+func conversionTemp(pass *analysis.Pass, parent ast.Node, call *ast.CallExpr) *types.Var {
+	var name *ast.Ident
+	switch parent := parent.(type) {
+	case *ast.AssignStmt:
+		if parent.Tok != token.DEFINE || len(parent.Lhs) != len(parent.Rhs) {
+			return nil
+		}
+		for i, rhs := range parent.Rhs {
+			if rhs == call {
+				name, _ = parent.Lhs[i].(*ast.Ident)
+			}
+		}
+	case *ast.ValueSpec:
+		if len(parent.Names) != len(parent.Values) {
+			return nil
+		}
+		for i, value := range parent.Values {
+			if value == call {
+				name = parent.Names[i]
+			}
+		}
+	}
+	if name == nil {
+		return nil
+	}
+	temp, ok := pass.TypesInfo.Defs[name].(*types.Var)
+	if !ok || temp.Parent() == pass.Pkg.Scope() {
+		return nil
+	}
+	return temp
+}
+
+func identUses(pass *analysis.Pass) map[types.Object][]*ast.Ident {
+	uses := make(map[types.Object][]*ast.Ident)
+	for ident, obj := range pass.TypesInfo.Uses {
+		if _, ok := obj.(*types.Var); ok {
+			uses[obj] = append(uses[obj], ident)
+		}
+	}
+	return uses
+}
+
+// loopInvariantBytes reports whether expr names a byte slice declared outside
+// loop that the loop only ever converts to a string, so every iteration
+// converts the same bytes.
+func loopInvariantBytes(pass *analysis.Pass, loop ast.Stmt, expr ast.Expr) bool {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	obj, ok := pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok || (loop.Pos() <= obj.Pos() && obj.Pos() < loop.End()) {
+		return false
+	}
+	converted := make(map[*ast.Ident]bool)
+	invariant := true
+	ast.Inspect(loop, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if isByteStringConversion(pass, n) {
+				if arg, ok := ast.Unparen(n.Args[0]).(*ast.Ident); ok {
+					converted[arg] = true
+				}
+			}
+		case *ast.Ident:
+			if pass.TypesInfo.Uses[n] == obj && !converted[n] {
+				invariant = false
+			}
+		}
+		return invariant
+	})
+	return invariant
+}
@@ -0,0 +1,79 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapKeyConversionFlagsTemporaryLookup(t *testing.T) {
+	src := `package sample
+
+func lookup(m map[string]int, b []byte) (int, bool) {
+	key := string(b)
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	var alt = string(b[1:])
+	return m[alt], false
+}
+`
+
+	diags := runAnalyzerOnSource(t, mapKeyConversionAnalyzer, "lookup.go", src)
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0].Message, "[perf_inline_byte_to_string_mapkey] string(b) is stored in key "+
+		"before a map lookup, which copies the bytes; index with m[string(b)] so the lookup does not allocate")
+	require.Contains(t, diags[1].Message, "string(b[1:]) is stored in alt")
+}
+
+func TestMapKeyConversionAllowsNeededStrings(t *testing.T) {
+	src := `package sample
+
+type bytes []byte
+
+func lookup(m map[string]int, b []byte, named bytes) (string, int) {
+	if v, ok := m[string(b)]; ok {
+		return "", v
+	}
+	kept := string(b)
+	if _, ok := m[kept]; !ok {
+		return kept, 0
+	}
+	stored := string(named)
+	m[stored] = m[stored] + 1
+	return "", m[string(named)]
+}
+`
+
+	diags := runAnalyzerOnSource(t, mapKeyConversionAnalyzer, "lookup_ok.go", src)
+	require.Empty(t, diags)
+}
+
+func TestMapKeyConversionFlagsRepeatedLoopConversion(t *testing.T) {
+	src := `package sample
+
+func tally(counts map[string]int, key []byte, words [][]byte, read func([]byte) int) {
+	for _, w := range words {
+		counts[string(key)]++
+		counts[string(w)]++
+		k := string(key)
+		counts[k] += len(w)
+	}
+	buf := make([]byte, 8)
+	for read(buf) > 0 {
+		counts[string(buf)]++
+	}
+	for range words {
+		if counts[string(key)] > 0 {
+			return
+		}
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, mapKeyConversionAnalyzer, "tally.go", src)
+	require.Len(t, diags, 2)
+	for _, diag := range diags {
+		require.Contains(t, diag.Message, "string(key) copies the same bytes into a new map key on every iteration")
+	}
+}
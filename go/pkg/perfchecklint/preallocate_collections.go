@@ -14,9 +14,11 @@ import (
 )
 
 var preallocateCollectionsAnalyzer = &analysis.Analyzer{
-	Name:     "perf_preallocate_collections",
-	Doc:      "reports slice growth in range loops without prior preallocation",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Name: "perf_preallocate_collections",
+	Doc:  "reports slice and map growth in counted loops without prior preallocation",
+	Requires: []*analysis.Analyzer{
+		inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer, callCostAnalyzer,
+	},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_preallocate_collections")
 		if !ok {
@@ -47,13 +49,27 @@ var preallocateCollectionsAnalyzer = &analysis.Analyzer{
 			analyzePreallocation(pass, body, rule)
 		})
 
+		reportCostlyCalls(pass, ins, costAlloc, rule)
+
 		return nil, nil
 	},
 }
 
 func analyzePreallocation(pass *analysis.Pass, body *ast.BlockStmt, rule ruleset.Rule) {
+	scanPreallocation(pass, body, func(pos token.Pos, message string) {
+		report(pass, pos, rule, message)
+	})
+}
+
+// growthFunc receives each collection that grows inside a loop without
+// reserving capacity first.
+type growthFunc func(pos token.Pos, message string)
+
+// scanPreallocation calls grow for every unsized append or map insert in body
+// that perf_preallocate_collections reports.
+func scanPreallocation(pass *analysis.Pass, body *ast.BlockStmt, grow growthFunc) {
 	env := make(map[string]bool, 8)
-	scanBlock(pass, body, env, false, rule)
+	scanBlock(pass, body, env, false, grow)
 }
 
 func scanBlock(
@@ -61,71 +77,72 @@ func scanBlock(
 	block *ast.BlockStmt,
 	reserved map[string]bool,
 	insideLoop bool,
-	rule ruleset.Rule,
+	grow growthFunc,
 ) {
 	if block == nil {
 		return
 	}
 	for _, stmt := range block.List {
-		scanStmt(pass, stmt, reserved, insideLoop, rule)
+		scanStmt(pass, stmt, reserved, insideLoop, grow)
 	}
 }
 
-func scanStmt(pass *analysis.Pass, stmt ast.Stmt, reserved map[string]bool, insideLoop bool, rule ruleset.Rule) {
+func scanStmt(pass *analysis.Pass, stmt ast.Stmt, reserved map[string]bool, insideLoop bool, grow growthFunc) {
 	switch s := stmt.(type) {
 	case *ast.AssignStmt:
 		if insideLoop {
-			checkAppend(pass, s, reserved, rule)
+			checkAppend(pass, s, reserved, grow)
+			checkMapInsert(pass, s, reserved, grow)
 		}
 		updateReservedFromAssign(s, reserved)
 	case *ast.DeclStmt:
 		updateReservedFromDecl(s, reserved)
 	case *ast.RangeStmt:
 		loopInside := insideLoop
-		if !loopInside && isSliceRange(pass.TypesInfo, s.X) {
+		if !loopInside && hasKnownLength(pass.TypesInfo, s.X) {
 			loopInside = true
 		}
 		loopReserved := cloneReserved(reserved)
 		// Range statements may declare new variables; process body accordingly.
-		scanBlock(pass, s.Body, loopReserved, loopInside, rule)
+		scanBlock(pass, s.Body, loopReserved, loopInside, grow)
 	case *ast.ForStmt:
 		loopReserved := cloneReserved(reserved)
 		if s.Init != nil {
-			scanStmt(pass, s.Init, loopReserved, insideLoop, rule)
+			scanStmt(pass, s.Init, loopReserved, insideLoop, grow)
 		}
-		scanBlock(pass, s.Body, loopReserved, insideLoop, rule)
+		scanBlock(pass, s.Body, loopReserved, insideLoop || isCountedLoop(s), grow)
 		if s.Post != nil {
-			scanStmt(pass, s.Post, loopReserved, insideLoop, rule)
+			scanStmt(pass, s.Post, loopReserved, insideLoop, grow)
 		}
 	case *ast.BlockStmt:
-		scanBlock(pass, s, cloneReserved(reserved), insideLoop, rule)
+		scanBlock(pass, s, cloneReserved(reserved), insideLoop, grow)
 	case *ast.IfStmt:
 		if s.Init != nil {
-			scanStmt(pass, s.Init, cloneReserved(reserved), insideLoop, rule)
+			scanStmt(pass, s.Init, cloneReserved(reserved), insideLoop, grow)
 		}
-		scanBlock(pass, s.Body, cloneReserved(reserved), insideLoop, rule)
+		scanBlock(pass, s.Body, cloneReserved(reserved), insideLoop, grow)
 		if elseBlock := asBlock(s.Else); elseBlock != nil {
-			scanBlock(pass, elseBlock, cloneReserved(reserved), insideLoop, rule)
+			scanBlock(pass, elseBlock, cloneReserved(reserved), insideLoop, grow)
 		}
 	case *ast.SwitchStmt:
 		if s.Init != nil {
-			scanStmt(pass, s.Init, cloneReserved(reserved), insideLoop, rule)
+			scanStmt(pass, s.Init, cloneReserved(reserved), insideLoop, grow)
 		}
 		for _, stmt := range s.Body.List {
 			if clause, ok := stmt.(*ast.CaseClause); ok {
-				scanBlock(pass, &ast.BlockStmt{List: clause.Body}, cloneReserved(reserved), insideLoop, rule)
+				scanBlock(pass, &ast.BlockStmt{List: clause.Body}, cloneReserved(reserved), insideLoop, grow)
 			}
 		}
 	case *ast.TypeSwitchStmt:
 		if s.Init != nil {
-			scanStmt(pass, s.Init, cloneReserved(reserved), insideLoop, rule)
+			scanStmt(pass, s.Init, cloneReserved(reserved), insideLoop, grow)
 		}
 		if s.Assign != nil {
-			scanStmt(pass, s.Assign, cloneReserved(reserved), insideLoop, rule)
+			scanStmt(pass, s.Assign, cloneReserved(reserved), insideLoop, grow)
 		}
 		for _, stmt := range s.Body.List {
 			if clause, ok := stmt.(*ast.CaseClause); ok {
-				scanBlock(pass, &ast.BlockStmt{List: clause.Body}, cloneReserved(reserved), insideLoop, rule)
+				scanBlock(pass, &ast.BlockStmt{List: clause.Body}, cloneReserved(reserved), insideLoop, grow)
 			}
 		}
 	default:
@@ -133,7 +150,7 @@ func scanStmt(pass *analysis.Pass, stmt ast.Stmt, reserved map[string]bool, insi
 	}
 }
 
-func checkAppend(pass *analysis.Pass, assign *ast.AssignStmt, reserved map[string]bool, rule ruleset.Rule) {
+func checkAppend(pass *analysis.Pass, assign *ast.AssignStmt, reserved map[string]bool, grow growthFunc) {
 	if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
 		return
 	}
@@ -161,26 +178,46 @@ func checkAppend(pass *analysis.Pass, assign *ast.AssignStmt, reserved map[strin
 		return
 	}
 
-	report(pass, assign.Pos(), rule, "append inside loop without preallocated capacity")
+	grow(assign.Pos(), "append inside loop without preallocated capacity")
 }
 
-func updateReservedFromAssign(assign *ast.AssignStmt, reserved map[string]bool) {
-	if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+func checkMapInsert(pass *analysis.Pass, assign *ast.AssignStmt, reserved map[string]bool, grow growthFunc) {
+	if assign.Tok != token.ASSIGN {
 		return
 	}
 
-	call := extractCall(assign.Rhs[0], "make")
-	if call == nil {
+	for _, lhs := range assign.Lhs {
+		index, ok := lhs.(*ast.IndexExpr)
+		if !ok {
+			continue
+		}
+		// Only maps made here without a size hint; callers own the others.
+		if sized, made := reserved[exprKey(index.X)]; !made || sized {
+			continue
+		}
+		if !isMapType(pass.TypesInfo, index.X) {
+			continue
+		}
+		grow(assign.Pos(), "map insert inside loop into a map made without a size hint")
 		return
 	}
+}
 
-	if !isPreallocMake(call) {
+// updateReservedFromAssign records collections created by assign: true when
+// they reserve capacity up front and false when they start empty.
+func updateReservedFromAssign(assign *ast.AssignStmt, reserved map[string]bool) {
+	if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return
+	}
+
+	sized, ok := reservation(assign.Rhs[0])
+	if !ok {
 		return
 	}
 
 	key := exprKey(assign.Lhs[0])
 	if key != "" {
-		reserved[key] = true
+		reserved[key] = sized
 	}
 }
 
@@ -199,18 +236,32 @@ func updateReservedFromDecl(decl *ast.DeclStmt, reserved map[string]bool) {
 			continue
 		}
 		for i, value := range valueSpec.Values {
-			call := extractCall(value, "make")
-			if call == nil || !isPreallocMake(call) {
+			sized, ok := reservation(value)
+			if !ok {
 				continue
 			}
 			name := valueSpec.Names[i]
 			if name != nil && name.Name != "" {
-				reserved[name.Name] = true
+				reserved[name.Name] = sized
 			}
 		}
 	}
 }
 
+// reservation reports whether expr creates a collection with make or an empty
+// map literal, and whether it reserves capacity for it.
+func reservation(expr ast.Expr) (sized, ok bool) {
+	if call := extractCall(expr, "make"); call != nil {
+		return isPreallocMake(call), true
+	}
+	if lit, isLit := expr.(*ast.CompositeLit); isLit && len(lit.Elts) == 0 {
+		if _, isMap := lit.Type.(*ast.MapType); isMap {
+			return false, true
+		}
+	}
+	return false, false
+}
+
 func extractCall(expr ast.Expr, name string) *ast.CallExpr {
 	call, ok := expr.(*ast.CallExpr)
 	if !ok {
@@ -263,7 +314,10 @@ func exprKey(expr ast.Expr) string {
 	}
 }
 
-func isSliceRange(info *types.Info, expr ast.Expr) bool {
+// hasKnownLength reports whether ranging over expr runs a number of
+// iterations known before the loop starts: slices, arrays, maps, and integers.
+// Channels, strings, and iterator functions do not qualify.
+func hasKnownLength(info *types.Info, expr ast.Expr) bool {
 	if info == nil {
 		return false
 	}
@@ -271,14 +325,49 @@ func isSliceRange(info *types.Info, expr ast.Expr) bool {
 	if t == nil {
 		return false
 	}
-	switch t.Underlying().(type) {
-	case *types.Array, *types.Slice:
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Array, *types.Slice, *types.Map:
 		return true
+	case *types.Basic:
+		return u.Info()&types.IsInteger != 0
 	default:
 		return false
 	}
 }
 
+// isCountedLoop reports whether loop counts up to a bound fixed on entry, as
+// in for i := 0; i < len(x); i++ or for i := 0; i < n; i++.
+func isCountedLoop(loop *ast.ForStmt) bool {
+	init, ok := loop.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE || len(init.Lhs) != 1 || len(init.Rhs) != 1 {
+		return false
+	}
+	counter, ok := init.Lhs[0].(*ast.Ident)
+	if !ok {
+		return false
+	}
+
+	cond, ok := loop.Cond.(*ast.BinaryExpr)
+	if !ok || (cond.Op != token.LSS && cond.Op != token.LEQ) || exprKey(cond.X) != counter.Name {
+		return false
+	}
+	switch bound := ast.Unparen(cond.Y).(type) {
+	case *ast.BasicLit, *ast.Ident:
+	case *ast.CallExpr:
+		if extractCall(bound, "len") == nil {
+			return false
+		}
+	default:
+		return false
+	}
+
+	post, ok := loop.Post.(*ast.IncDecStmt)
+	return ok && post.Tok == token.INC && exprKey(post.X) == counter.Name
+}
+
 func isSliceType(info *types.Info, expr ast.Expr) bool {
 	if info == nil {
 		return false
@@ -291,6 +380,18 @@ func isSliceType(info *types.Info, expr ast.Expr) bool {
 	return ok
 }
 
+func isMapType(info *types.Info, expr ast.Expr) bool {
+	if info == nil {
+		return false
+	}
+	t := info.TypeOf(expr)
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Map)
+	return ok
+}
+
 func asBlock(stmt ast.Stmt) *ast.BlockStmt {
 	switch s := stmt.(type) {
 	case *ast.BlockStmt:
@@ -60,6 +60,84 @@ func build(items []int) []int {
 	require.Empty(t, diags)
 }
 
+func TestPreallocateCollectionsDetectsUnsizedMapInserts(t *testing.T) {
+	src := `package sample
+
+func invert(index map[string]int, names []string) (map[int]string, map[string]bool) {
+	out := make(map[int]string)
+	for name, id := range index {
+		out[id] = name
+	}
+	seen := map[string]bool{}
+	for i := 0; i < len(names); i++ {
+		seen[names[i]] = true
+	}
+	return out, seen
+}
+`
+	diags := runPreallocate(src)
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0].Message, "map insert inside loop into a map made without a size hint")
+	require.True(t, containsRule(diags, "perf_preallocate_collections"))
+}
+
+func TestPreallocateCollectionsIgnoresSizedAndForeignMaps(t *testing.T) {
+	src := `package sample
+
+func fill(dst map[string]int, names []string) map[string]int {
+	out := make(map[string]int, len(names))
+	for i, name := range names {
+		out[name] = i
+		dst[name] = i
+		out[name]++
+	}
+	return out
+}
+`
+	diags := runPreallocate(src)
+	require.Empty(t, diags)
+}
+
+func TestPreallocateCollectionsDetectsCountedLoops(t *testing.T) {
+	src := `package sample
+
+func squares(n int, items []string) ([]int, []string) {
+	var out []int
+	for i := range n {
+		out = append(out, i*i)
+	}
+	var copied []string
+	for i := 0; i < len(items); i++ {
+		copied = append(copied, items[i])
+	}
+	return out, copied
+}
+`
+	diags := runPreallocate(src)
+	require.Len(t, diags, 2)
+}
+
+func TestPreallocateCollectionsIgnoresUnboundedLoops(t *testing.T) {
+	src := `package sample
+
+func drain(ch <-chan int, next func() (int, bool)) []int {
+	var out []int
+	for v := range ch {
+		out = append(out, v)
+	}
+	for v, ok := next(); ok; v, ok = next() {
+		out = append(out, v)
+	}
+	for i := 0; i < 10; i += 3 {
+		out = append(out, i)
+	}
+	return out
+}
+`
+	diags := runPreallocate(src)
+	require.Empty(t, diags)
+}
+
 func runPreallocate(src string) []analysis.Diagnostic {
 	fset := token.NewFileSet()
 	file, err := parser.ParseFile(fset, "preallocate.go", src, parser.ParseComments)
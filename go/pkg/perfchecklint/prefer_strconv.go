@@ -0,0 +1,376 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+var preferStrconvAnalyzer = &analysis.Analyzer{
+	Name:     "perf_prefer_strconv",
+	Doc:      "reports fmt.Sprint/Sprintf calls that only format a single scalar",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_prefer_strconv")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_prefer_strconv not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		// Collect every rewritable call first: whether a fix may drop the
+		// fmt import depends on the other calls in the same file.
+		var calls []scalarCall
+		rewritten := make(map[*ast.File][]scalarCall)
+		ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+			call, _ := node.(*ast.CallExpr)
+			rewrite, ok := scalarFormatCall(pass, call)
+			if !ok {
+				return
+			}
+			c := scalarCall{call: call, rewrite: rewrite}
+			calls = append(calls, c)
+			if file := fileFor(pass, call.Pos()); file != nil && rewriteImportable(pass, file, call, rewrite) {
+				rewritten[file] = append(rewritten[file], c)
+			}
+		})
+
+		fmtImports := make(map[*ast.File]fmtImport)
+		for _, c := range calls {
+			detail := fmt.Sprintf("%s boxes its argument and formats it through reflection; use %s",
+				c.rewrite.callee, c.rewrite.display)
+			if fix, ok := strconvFix(pass, c.call, c.rewrite, rewritten, fmtImports); ok {
+				reportWithFixes(pass, c.call, rule, detail, fix)
+				continue
+			}
+			report(pass, c.call.Pos(), rule, detail)
+		}
+
+		return nil, nil
+	},
+}
+
+// scalarCall is a fmt call that formats a single scalar and its rewrite.
+type scalarCall struct {
+	call    *ast.CallExpr
+	rewrite scalarRewrite
+}
+
+// scalarRewrite describes the fmt-free replacement of a scalar formatting
+// call: pkg.fn(conv(arg), extra...), or just conv(arg) when pkg is empty.
+type scalarRewrite struct {
+	callee  string
+	arg     ast.Expr
+	pkg     string
+	fn      string
+	conv    string
+	extra   string
+	display string
+}
+
+// scalarFormatCall matches fmt.Sprint(x) and fmt.Sprintf(verb, x) whose
+// format is a lone %v, %d, %s, %t, or %x, where x is an integer, bool, or
+// string, or a byte slice formatted with %x. Types with methods are skipped:
+// fmt would call their String, Error, or Format method instead.
+func scalarFormatCall(pass *analysis.Pass, call *ast.CallExpr) (scalarRewrite, bool) {
+	if call == nil || call.Ellipsis.IsValid() {
+		return scalarRewrite{}, false
+	}
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "fmt" {
+		return scalarRewrite{}, false
+	}
+	var verb string
+	switch {
+	case fn.Name() == "Sprint" && len(call.Args) == 1:
+		verb = "%v"
+	case fn.Name() == "Sprintf" && len(call.Args) == 2:
+		tv := pass.TypesInfo.Types[call.Args[0]]
+		if tv.Value == nil || tv.Value.Kind() != constant.String {
+			return scalarRewrite{}, false
+		}
+		verb = constant.StringVal(tv.Value)
+	default:
+		return scalarRewrite{}, false
+	}
+
+	arg := call.Args[len(call.Args)-1]
+	typ := pass.TypesInfo.TypeOf(arg)
+	if typ == nil || types.NewMethodSet(types.NewPointer(typ)).Len() > 0 {
+		return scalarRewrite{}, false
+	}
+	rewrite := scalarRewrite{callee: "fmt." + fn.Name(), arg: arg}
+	if fn.Name() == "Sprintf" {
+		rewrite.callee = fmt.Sprintf("fmt.Sprintf(%q, ...)", verb)
+	}
+
+	if slice, ok := typ.Underlying().(*types.Slice); ok {
+		elem, ok := slice.Elem().Underlying().(*types.Basic)
+		if verb != "%x" || !ok || elem.Kind() != types.Byte {
+			return scalarRewrite{}, false
+		}
+		rewrite.pkg, rewrite.fn, rewrite.display = "encoding/hex", "EncodeToString", "hex.EncodeToString"
+		return rewrite, true
+	}
+
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		return scalarRewrite{}, false
+	}
+	info := basic.Info()
+	switch {
+	case info&types.IsInteger != 0 && (verb == "%v" || verb == "%d"):
+		rewrite.pkg = "strconv"
+		switch {
+		case basic.Kind() == types.Int:
+			rewrite.fn, rewrite.conv = "Itoa", "int"
+		case info&types.IsUnsigned != 0:
+			rewrite.fn, rewrite.conv, rewrite.extra = "FormatUint", "uint64", ", 10"
+		default:
+			rewrite.fn, rewrite.conv, rewrite.extra = "FormatInt", "int64", ", 10"
+		}
+	case info&types.IsBoolean != 0 && (verb == "%v" || verb == "%t"):
+		rewrite.pkg, rewrite.fn, rewrite.conv = "strconv", "FormatBool", "bool"
+	case info&types.IsString != 0 && (verb == "%v" || verb == "%s"):
+		rewrite.conv, rewrite.display = "string", "the string itself"
+	default:
+		return scalarRewrite{}, false
+	}
+	if target := types.Universe.Lookup(rewrite.conv); target != nil && types.Identical(typ, target.Type()) {
+		rewrite.conv = ""
+	}
+	if rewrite.display == "" {
+		rewrite.display = "strconv." + rewrite.fn
+	}
+	return rewrite, true
+}
+
+// strconvFix replaces the fmt call with its scalar rewrite, importing the
+// replacement package. When the file's rewritable calls account for every fmt
+// reference, all of their fixes share one import edit that swaps fmt for the
+// packages they add, so applying them together leaves no unused import.
+// fmtImports caches that per-file plan.
+func strconvFix(
+	pass *analysis.Pass,
+	call *ast.CallExpr,
+	rewrite scalarRewrite,
+	rewritten map[*ast.File][]scalarCall,
+	fmtImports map[*ast.File]fmtImport,
+) (analysis.SuggestedFix, bool) {
+	file := fileFor(pass, call.Pos())
+	if file == nil {
+		return analysis.SuggestedFix{}, false
+	}
+
+	var importEdits []analysis.TextEdit
+	prefix, suffix := "", ""
+	if rewrite.pkg != "" {
+		qualifier, added, ok := importQualifier(pass, file, call.Pos(), rewrite.pkg)
+		if !ok {
+			return analysis.SuggestedFix{}, false
+		}
+		importEdits = added
+		prefix, suffix = qualifier+"."+rewrite.fn+"(", rewrite.extra+")"
+	}
+	switch {
+	case rewrite.conv != "":
+		prefix, suffix = prefix+rewrite.conv+"(", ")"+suffix
+	case rewrite.pkg == "" && !isOperand(rewrite.arg):
+		prefix, suffix = "(", ")"
+	}
+
+	imp, cached := fmtImports[file]
+	if !cached {
+		imp = planFmtImport(pass, file, rewritten[file])
+		fmtImports[file] = imp
+	}
+	if imp.drop {
+		if imp.edits == nil {
+			return analysis.SuggestedFix{}, false
+		}
+		importEdits = imp.edits
+	}
+
+	edits := append(importEdits,
+		analysis.TextEdit{Pos: call.Pos(), End: rewrite.arg.Pos(), NewText: []byte(prefix)},
+		analysis.TextEdit{Pos: rewrite.arg.End(), End: call.End(), NewText: []byte(suffix)},
+	)
+	return analysis.SuggestedFix{
+		Message:   fmt.Sprintf("Replace %s with %s", rewrite.callee, rewrite.display),
+		TextEdits: edits,
+	}, true
+}
+
+// isOperand reports whether expr can replace a call expression without
+// parentheses.
+func isOperand(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.Ident, *ast.BasicLit, *ast.SelectorExpr, *ast.CallExpr, *ast.IndexExpr, *ast.ParenExpr,
+		*ast.SliceExpr:
+		return true
+	}
+	return false
+}
+
+// rewriteImportable reports whether the package rewrite calls into can be
+// referenced at call, so that its fix can be offered.
+func rewriteImportable(pass *analysis.Pass, file *ast.File, call *ast.CallExpr, rewrite scalarRewrite) bool {
+	if rewrite.pkg == "" {
+		return true
+	}
+	_, _, ok := importQualifier(pass, file, call.Pos(), rewrite.pkg)
+	return ok
+}
+
+// fmtImport is the plan for a file's fmt import: whether the rewritable calls
+// remove every reference to it, and the import edits each of their fixes then
+// shares, or nil when fmt cannot be dropped cleanly.
+type fmtImport struct {
+	drop  bool
+	edits []analysis.TextEdit
+}
+
+// planFmtImport decides whether the calls in rewritten leave fmt unused in
+// file and, if so, builds the edit that replaces its import with the packages
+// their rewrites add, or deletes it when they add none.
+func planFmtImport(pass *analysis.Pass, file *ast.File, rewritten []scalarCall) fmtImport {
+	spec, refs, removed := countFmtRefs(pass, file, rewritten)
+	if spec == nil || removed != refs {
+		return fmtImport{}
+	}
+
+	var paths []string
+	for _, c := range rewritten {
+		if c.rewrite.pkg == "" || slices.Contains(paths, c.rewrite.pkg) {
+			continue
+		}
+		if _, added, ok := importQualifier(pass, file, c.call.Pos(), c.rewrite.pkg); ok && len(added) > 0 {
+			paths = append(paths, c.rewrite.pkg)
+		}
+	}
+	if len(paths) == 0 {
+		edit, ok := deleteImportEdit(pass, file, spec)
+		if !ok {
+			return fmtImport{drop: true}
+		}
+		return fmtImport{drop: true, edits: []analysis.TextEdit{edit}}
+	}
+
+	slices.Sort(paths)
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = strconv.Quote(path)
+	}
+	text := quoted[0]
+	if len(quoted) > 1 {
+		if importGroup(file, spec) {
+			text = strings.Join(quoted, "\n"+lineIndent(pass, spec.Pos()))
+		} else {
+			text = "(\n\t" + strings.Join(quoted, "\n\t") + "\n)"
+		}
+	}
+	return fmtImport{drop: true, edits: []analysis.TextEdit{{Pos: spec.Pos(), End: spec.End(), NewText: []byte(text)}}}
+}
+
+// importGroup reports whether spec sits in a parenthesized import declaration.
+func importGroup(file *ast.File, spec *ast.ImportSpec) bool {
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && slices.Contains(gen.Specs, ast.Spec(spec)) {
+			return gen.Lparen.IsValid()
+		}
+	}
+	return false
+}
+
+// countFmtRefs finds the fmt import of file, counts its references, and counts
+// those made by the callee of each call in rewritten.
+func countFmtRefs(pass *analysis.Pass, file *ast.File, rewritten []scalarCall) (*ast.ImportSpec, int, int) {
+	var spec *ast.ImportSpec
+	var pkgName *types.PkgName
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || path != "fmt" {
+			continue
+		}
+		obj := pass.TypesInfo.Implicits[imp]
+		if imp.Name != nil {
+			obj = pass.TypesInfo.Defs[imp.Name]
+		}
+		if name, ok := obj.(*types.PkgName); ok {
+			spec, pkgName = imp, name
+		}
+	}
+	if spec == nil {
+		return nil, 0, 0
+	}
+
+	refs, removed := 0, 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && pass.TypesInfo.Uses[ident] == pkgName {
+			refs++
+		}
+		return true
+	})
+	for _, c := range rewritten {
+		if sel, ok := ast.Unparen(c.call.Fun).(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && pass.TypesInfo.Uses[ident] == pkgName {
+				removed++
+			}
+		}
+	}
+	return spec, refs, removed
+}
+
+// deleteImportEdit removes spec from the file, along with its import
+// declaration when it is the only spec. It fails when spec shares its line
+// with another spec.
+func deleteImportEdit(pass *analysis.Pass, file *ast.File, spec *ast.ImportSpec) (analysis.TextEdit, bool) {
+	tf := pass.Fset.File(spec.Pos())
+	if tf == nil {
+		return analysis.TextEdit{}, false
+	}
+	lineSpan := func(start, end token.Pos) analysis.TextEdit {
+		from := tf.LineStart(tf.Line(start))
+		to := token.Pos(tf.Base() + tf.Size())
+		if line := tf.Line(end); line < tf.LineCount() {
+			to = tf.LineStart(line + 1)
+		}
+		return analysis.TextEdit{Pos: from, End: to}
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for i, s := range gen.Specs {
+			if s != spec {
+				continue
+			}
+			if len(gen.Specs) == 1 {
+				return lineSpan(gen.Pos(), gen.End()), true
+			}
+			line := tf.Line(spec.Pos())
+			if (i > 0 && tf.Line(gen.Specs[i-1].End()) == line) ||
+				(i+1 < len(gen.Specs) && tf.Line(gen.Specs[i+1].Pos()) == line) {
+				return analysis.TextEdit{}, false
+			}
+			return lineSpan(spec.Pos(), spec.End()), true
+		}
+	}
+	return analysis.TextEdit{}, false
+}
@@ -0,0 +1,179 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferStrconvRewritesScalarFormatting(t *testing.T) {
+	src := `package sample
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type name string
+
+func describe(n int, u uint8, i64 int64, ok bool, s string, nm name, key []byte) []string {
+	return []string{
+		fmt.Sprintf("%d", n),
+		fmt.Sprint(u),
+		fmt.Sprintf("%v", i64),
+		fmt.Sprintf("%t", ok),
+		fmt.Sprintf("%s", s+"!"),
+		fmt.Sprint(nm),
+		fmt.Sprintf("%x", key),
+		strconv.Quote(s),
+	}
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, preferStrconvAnalyzer, "describe.go", src)
+	require.Len(t, diags, 7)
+	require.Contains(t, diags[0].Message, "[perf_prefer_strconv] fmt.Sprintf(\"%d\", ...) boxes its argument "+
+		"and formats it through reflection; use strconv.Itoa")
+	require.Contains(t, diags[1].Message, "fmt.Sprint boxes its argument")
+
+	rewrites := []string{
+		"\t\tstrconv.Itoa(n),\n",
+		"\t\tstrconv.FormatUint(uint64(u), 10),\n",
+		"\t\tstrconv.FormatInt(i64, 10),\n",
+		"\t\tstrconv.FormatBool(ok),\n",
+		"\t\t(s+\"!\"),\n",
+		"\t\tstring(nm),\n",
+		"\t\thex.EncodeToString(key),\n",
+	}
+	for i, want := range rewrites {
+		require.Contains(t, applySuggestedFix(t, fset, src, diags[i]), want)
+	}
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[6]), "import (\n\t\"encoding/hex\"\n\t\"strconv\"\n)")
+
+	fixed := applyAllSuggestedFixes(t, fset, src, diags)
+	require.Contains(t, fixed, "import (\n\t\"encoding/hex\"\n\t\"strconv\"\n)")
+	require.Empty(t, runAnalyzerOnSource(t, preferStrconvAnalyzer, "describe.go", fixed))
+}
+
+func TestPreferStrconvFixDropsUnusedFmtImport(t *testing.T) {
+	src := `package sample
+
+import "fmt"
+
+func itoa(n int) string {
+	return fmt.Sprint(n)
+}
+`
+	want := `package sample
+
+import "strconv"
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, preferStrconvAnalyzer, "itoa.go", src)
+	require.Len(t, diags, 1)
+	require.Equal(t, want, applySuggestedFix(t, fset, src, diags[0]))
+
+	src = `package sample
+
+import (
+	"fmt"
+	"strings"
+)
+
+func upper(s string) string {
+	return strings.ToUpper(fmt.Sprint(s))
+}
+`
+	fset, diags = runAnalyzerOnSourceWithFileSet(t, preferStrconvAnalyzer, "upper.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[0]),
+		"import (\n\t\"strings\"\n)\n\nfunc upper(s string) string {\n\treturn strings.ToUpper(s)\n}")
+}
+
+func TestPreferStrconvFixesTogetherDropFmt(t *testing.T) {
+	src := `package sample
+
+import "fmt"
+
+func show(i int, b bool, s string) (string, string, string) {
+	return fmt.Sprint(i), fmt.Sprint(b), fmt.Sprint(s)
+}
+`
+	want := `package sample
+
+import "strconv"
+
+func show(i int, b bool, s string) (string, string, string) {
+	return strconv.Itoa(i), strconv.FormatBool(b), s
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, preferStrconvAnalyzer, "show.go", src)
+	require.Len(t, diags, 3)
+	fixed := applyAllSuggestedFixes(t, fset, src, diags)
+	require.Equal(t, want, fixed)
+	// Type-checks the result: an unused fmt import would fail here.
+	require.Empty(t, runAnalyzerOnSource(t, preferStrconvAnalyzer, "show.go", fixed))
+}
+
+func TestPreferStrconvFixKeepsFmtWhenStillUsed(t *testing.T) {
+	src := `package sample
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func show(s string, n int) (string, string) {
+	return fmt.Sprintf("%s", s), fmt.Sprintf("%5d", n) + strconv.Itoa(n)
+}
+
+func only(s string) string {
+	return fmt.Sprint(s)
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, preferStrconvAnalyzer, "show.go", src)
+	require.Len(t, diags, 2)
+	fixed := applySuggestedFix(t, fset, src, diags[0])
+	require.Contains(t, fixed, "import (\n\t\"fmt\"\n\t\"strconv\"\n)")
+	require.Contains(t, fixed, "return s, fmt.Sprintf(\"%5d\", n)")
+}
+
+func TestPreferStrconvIgnoresFormattersAndComplexFormats(t *testing.T) {
+	src := `package sample
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type level int
+
+func (l level) String() string { return "level" }
+
+func render(l level, d time.Duration, err error, f float64, n int, args []any) []string {
+	return []string{
+		fmt.Sprint(l),
+		fmt.Sprintf("%d", l),
+		fmt.Sprint(d),
+		fmt.Sprint(err),
+		fmt.Sprint(f),
+		fmt.Sprintf("%x", n),
+		fmt.Sprintf("%q", "s"),
+		fmt.Sprintf("n=%d", n),
+		fmt.Sprint(n, n),
+		fmt.Sprint(args...),
+		fmt.Sprint(errors.New("x")),
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, preferStrconvAnalyzer, "render.go", src)
+	require.Empty(t, diags)
+}
@@ -0,0 +1,302 @@
+package perfchecklint
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/google/pprof/profile"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/astutil"
+)
+
+// ProfileEnv names the environment variable that points the unitchecker
+// binary at a pprof profile, taking precedence over the configuration.
+const ProfileEnv = "PERFCHECK_PROFILE"
+
+// DefaultHotPercent is the cumulative sample share at which a function is
+// considered hot when the configuration leaves hot_percent unset.
+const DefaultHotPercent = 5.0
+
+// ProfileConfig points perfcheck at a pprof CPU or allocation profile, such as
+// the default.pgo used for profile-guided optimization.
+//
+// Path is resolved relative to the configuration file. SampleType selects the
+// profile value to weigh by (for example "cpu" or "alloc_space") and defaults
+// to the profile's default sample type. Findings in functions whose
+// cumulative share reaches HotPercent have their severity raised one level;
+// with DropCold set, findings in functions at or below ColdPercent are
+// dropped.
+type ProfileConfig struct {
+	Path        string  `yaml:"path"`
+	SampleType  string  `yaml:"sample_type"`
+	HotPercent  float64 `yaml:"hot_percent"`
+	ColdPercent float64 `yaml:"cold_percent"`
+	DropCold    bool    `yaml:"drop_cold"`
+}
+
+func (p ProfileConfig) validate() error {
+	var errs []error
+	if p.HotPercent < 0 || p.HotPercent > 100 {
+		errs = append(errs, fmt.Errorf("profile: hot_percent must be within [0, 100], got %g", p.HotPercent))
+	}
+	if p.ColdPercent < 0 || p.ColdPercent > 100 {
+		errs = append(errs, fmt.Errorf("profile: cold_percent must be within [0, 100], got %g", p.ColdPercent))
+	}
+	if p.ColdPercent >= p.hotPercent() {
+		errs = append(errs, fmt.Errorf("profile: cold_percent %g must be below hot_percent %g",
+			p.ColdPercent, p.hotPercent()))
+	}
+	return errors.Join(errs...)
+}
+
+func (p ProfileConfig) hotPercent() float64 {
+	if p.HotPercent == 0 {
+		return DefaultHotPercent
+	}
+	return p.HotPercent
+}
+
+// Profile holds the cumulative sample weight of every function in a pprof
+// profile. Closures and generic instantiations are folded into the function
+// declaration that encloses them, matching how perfcheck reports findings.
+type Profile struct {
+	SampleType string
+	Total      int64
+	weights    map[string]int64
+}
+
+// LoadProfile reads a pprof profile and weighs functions by sampleType, or by
+// the profile's default sample type when sampleType is empty.
+func LoadProfile(filename, sampleType string) (*Profile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("perfcheck profile: %w", err)
+	}
+	defer f.Close()
+	parsed, err := profile.Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("perfcheck profile %s: %w", filename, err)
+	}
+	prof, err := newProfile(parsed, sampleType)
+	if err != nil {
+		return nil, fmt.Errorf("perfcheck profile %s: %w", filename, err)
+	}
+	return prof, nil
+}
+
+func newProfile(parsed *profile.Profile, sampleType string) (*Profile, error) {
+	index, err := sampleIndex(parsed, sampleType)
+	if err != nil {
+		return nil, err
+	}
+	prof := &Profile{SampleType: parsed.SampleType[index].Type, weights: make(map[string]int64)}
+	for _, sample := range parsed.Sample {
+		value := sample.Value[index]
+		if value == 0 {
+			continue
+		}
+		prof.Total += value
+		// Recursion and inlining can name a function several times in one
+		// stack; cumulative weight counts each sample once per function.
+		seen := make(map[string]bool)
+		for _, loc := range sample.Location {
+			for _, line := range loc.Line {
+				if line.Function == nil {
+					continue
+				}
+				name := normalizeProfileSymbol(line.Function.Name)
+				if !seen[name] {
+					seen[name] = true
+					prof.weights[name] += value
+				}
+			}
+		}
+	}
+	return prof, nil
+}
+
+func sampleIndex(parsed *profile.Profile, sampleType string) (int, error) {
+	if len(parsed.SampleType) == 0 {
+		return 0, errors.New("profile has no sample types")
+	}
+	want := sampleType
+	if want == "" {
+		want = parsed.DefaultSampleType
+	}
+	if want == "" {
+		return len(parsed.SampleType) - 1, nil
+	}
+	var names []string
+	for i, st := range parsed.SampleType {
+		if st.Type == want {
+			return i, nil
+		}
+		names = append(names, st.Type)
+	}
+	return 0, fmt.Errorf("sample type %q not found (have %s)", want, strings.Join(names, ", "))
+}
+
+// Share returns the cumulative percentage of samples attributed to symbol, a
+// pprof-style function name such as "example.com/pkg.(*T).Method".
+func (p *Profile) Share(symbol string) float64 {
+	if p == nil || p.Total == 0 {
+		return 0
+	}
+	return 100 * float64(p.weights[symbol]) / float64(p.Total)
+}
+
+// normalizeProfileSymbol drops generic instantiation brackets and closure,
+// go-statement, and defer wrapper suffixes so "pkg.F[...].func1.2" and
+// "pkg.F" share a weight.
+func normalizeProfileSymbol(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	depth := 0
+	for _, r := range name {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	name = b.String()
+
+	for {
+		dot := strings.LastIndexByte(name, '.')
+		if dot <= strings.LastIndexByte(name, '/') || !isWrapperSuffix(name[dot+1:]) {
+			return name
+		}
+		name = name[:dot]
+	}
+}
+
+func isWrapperSuffix(part string) bool {
+	for _, prefix := range []string{"func", "gowrap", "deferwrap", ""} {
+		if rest, ok := strings.CutPrefix(part, prefix); ok && rest != "" && isDigits(rest) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(text string) bool {
+	return !strings.ContainsFunc(text, func(r rune) bool { return r < '0' || r > '9' })
+}
+
+// hotness applies the configured thresholds to a loaded profile.
+type hotness struct {
+	profile  *Profile
+	hot      float64
+	cold     float64
+	dropCold bool
+}
+
+// weigh returns the display name and sample share of the function declaration
+// enclosing pos; ok is false without a profile or outside any function.
+func (h *hotness) weigh(pass *analysis.Pass, pos token.Pos) (name string, share float64, ok bool) {
+	if h == nil {
+		return "", 0, false
+	}
+	decl := astutil.EnclosingFuncDecl(pass.Files, pos)
+	if decl == nil {
+		return "", 0, false
+	}
+	path := pass.Pkg.Path()
+	if pass.Pkg.Name() == "main" {
+		path = "main"
+	}
+	member := astutil.FuncName(decl)
+	return pass.Pkg.Name() + "." + member, h.profile.Share(path + "." + member), true
+}
+
+var (
+	profileFile  string
+	profileCache sync.Map // absolute path + "\x00" + sample type -> *profileEntry
+)
+
+type profileEntry struct {
+	once    sync.Once
+	profile *Profile
+	err     error
+}
+
+func cachedProfile(filename, sampleType string) (*Profile, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := profileCache.LoadOrStore(abs+"\x00"+sampleType, &profileEntry{})
+	entry, _ := value.(*profileEntry)
+	entry.once.Do(func() {
+		entry.profile, entry.err = LoadProfile(abs, sampleType)
+	})
+	return entry.profile, entry.err
+}
+
+// profileAnalyzer loads the pprof profile named by the -profile flag,
+// PERFCHECK_PROFILE, or the configuration, in that order, so emit can weigh
+// findings by how hot their enclosing function is.
+var profileAnalyzer = &analysis.Analyzer{
+	Name:       "perfcheck_profile",
+	Doc:        "loads the pprof profile used to weigh perfcheck diagnostics",
+	Requires:   []*analysis.Analyzer{configAnalyzer},
+	ResultType: reflect.TypeOf((*hotness)(nil)),
+	Run: func(pass *analysis.Pass) (any, error) {
+		return resolveHotness(pass, profileFile)
+	},
+}
+
+// newProfileAnalyzer returns a profile analyzer that reads its configuration
+// from config and prefers filename over the configured profile. It stands in
+// for profileAnalyzer in bundles built by Build.
+func newProfileAnalyzer(config *analysis.Analyzer, filename string) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:       profileAnalyzer.Name,
+		Doc:        profileAnalyzer.Doc,
+		Requires:   []*analysis.Analyzer{config},
+		ResultType: profileAnalyzer.ResultType,
+		Run: func(pass *analysis.Pass) (any, error) {
+			return resolveHotness(pass, filename)
+		},
+	}
+}
+
+func resolveHotness(pass *analysis.Pass, override string) (*hotness, error) {
+	cfg := passConfig(pass)
+	var settings ProfileConfig
+	if cfg != nil {
+		settings = cfg.Profile
+	}
+	filename := cfg.ProfilePath()
+	if override != "" {
+		filename = override
+	}
+	if filename == "" {
+		return nil, nil
+	}
+	prof, err := cachedProfile(filename, settings.SampleType)
+	if err != nil {
+		return nil, err
+	}
+	return &hotness{
+		profile:  prof,
+		hot:      settings.hotPercent(),
+		cold:     settings.ColdPercent,
+		dropCold: settings.DropCold,
+	}, nil
+}
+
+func init() {
+	profileAnalyzer.Flags.StringVar(&profileFile, "profile", "",
+		"path to a pprof CPU or allocation profile used to weigh diagnostics")
+}
@@ -0,0 +1,147 @@
+package perfchecklint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/stretchr/testify/require"
+)
+
+const weightedSource = `package sample
+
+type closers []interface{ Close() error }
+
+func (c *closers) hot() {
+	for _, f := range *c {
+		defer f.Close()
+	}
+}
+
+func cold(files closers) {
+	for _, f := range files {
+		defer f.Close()
+	}
+}
+`
+
+// writeProfile writes a CPU profile whose samples have the given stacks,
+// leaf first, and returns its path.
+func writeProfile(t *testing.T, stacks map[string]int64) string {
+	t.Helper()
+
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		PeriodType: &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:     1,
+	}
+	functions := make(map[string]*profile.Location)
+	for stack, value := range stacks {
+		sample := &profile.Sample{Value: []int64{1, value}}
+		for _, name := range strings.Split(stack, ";") {
+			loc, ok := functions[name]
+			if !ok {
+				id := uint64(len(functions) + 1)
+				fn := &profile.Function{ID: id, Name: name, SystemName: name}
+				loc = &profile.Location{ID: id, Line: []profile.Line{{Function: fn}}}
+				functions[name] = loc
+				prof.Function = append(prof.Function, fn)
+				prof.Location = append(prof.Location, loc)
+			}
+			sample.Location = append(sample.Location, loc)
+		}
+		prof.Sample = append(prof.Sample, sample)
+	}
+	require.NoError(t, prof.CheckValid())
+
+	path := filepath.Join(t.TempDir(), "default.pgo")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, prof.Write(f))
+	require.NoError(t, f.Close())
+	return path
+}
+
+func TestNormalizeProfileSymbol(t *testing.T) {
+	cases := map[string]string{
+		"example.com/pkg.Func":                      "example.com/pkg.Func",
+		"example.com/pkg.Func.func1":                "example.com/pkg.Func",
+		"example.com/pkg.Func.func1.2":              "example.com/pkg.Func",
+		"example.com/pkg.(*List[...]).Push":         "example.com/pkg.(*List).Push",
+		"example.com/pkg.Map[go.shape.int].gowrap1": "example.com/pkg.Map",
+		"example.com/pkg.init.0":                    "example.com/pkg.init",
+		"example.com/v2.Func.deferwrap3":            "example.com/v2.Func",
+		"example.com/pkg.T.func9x":                  "example.com/pkg.T.func9x",
+	}
+	for in, want := range cases {
+		require.Equal(t, want, normalizeProfileSymbol(in), in)
+	}
+}
+
+func TestLoadProfileWeighsCumulativeSamples(t *testing.T) {
+	path := writeProfile(t, map[string]int64{
+		"sample.leaf;sample.Run.func1;sample.Run": 60,
+		"sample.Run;sample.Run.func2;sample.main": 30,
+		"sample.other": 10,
+	})
+
+	prof, err := LoadProfile(path, "")
+	require.NoError(t, err)
+	require.Equal(t, "cpu", prof.SampleType)
+	require.Equal(t, int64(100), prof.Total)
+	require.InDelta(t, 90.0, prof.Share("sample.Run"), 1e-9, "closures fold into Run once per sample")
+	require.InDelta(t, 60.0, prof.Share("sample.leaf"), 1e-9)
+	require.Zero(t, prof.Share("sample.missing"))
+
+	prof, err = LoadProfile(path, "samples")
+	require.NoError(t, err)
+	require.InDelta(t, 100.0/3, prof.Share("sample.other"), 1e-9)
+
+	_, err = LoadProfile(path, "alloc_space")
+	require.ErrorContains(t, err, `sample type "alloc_space" not found (have samples, cpu)`)
+}
+
+func TestProfileWeightsDiagnostics(t *testing.T) {
+	path := writeProfile(t, map[string]int64{
+		"sample.(*closers).hot.func1;sample.(*closers).hot": 99,
+		"sample.cold": 1,
+	})
+
+	bundle := Build(BuildOptions{ProfileFile: path, Config: &Config{Rules: Selection{Include: []string{"perf_no_defer_in_loop"}}}})
+	diags := runAnalyzerOnSource(t, bundle.Analyzers[0], "sample.go", weightedSource)
+	require.Len(t, diags, 2)
+	require.True(t, strings.HasSuffix(diags[0].Message,
+		" Profile: sample.(*closers).hot carries 99.0% of cpu samples. Hot path."), diags[0].Message)
+	require.True(t, strings.HasSuffix(diags[1].Message,
+		" Profile: sample.cold carries 1.0% of cpu samples."), diags[1].Message)
+
+	bundle = Build(BuildOptions{
+		ProfileFile: path,
+		Config: &Config{
+			Rules:   Selection{Include: []string{"perf_no_defer_in_loop"}},
+			Profile: ProfileConfig{HotPercent: 50, ColdPercent: 2, DropCold: true},
+		},
+	})
+	diags = runAnalyzerOnSource(t, bundle.Analyzers[0], "sample.go", weightedSource)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "sample.(*closers).hot")
+}
+
+func TestProfileConfigValidation(t *testing.T) {
+	_, err := ParseConfig([]byte("profile:\n  hot_percent: 120\n  cold_percent: 150\n"))
+	require.ErrorContains(t, err, "hot_percent must be within [0, 100]")
+	require.ErrorContains(t, err, "cold_percent 150 must be below hot_percent 120")
+
+	cfg, err := ParseConfig([]byte("profile:\n  path: default.pgo\n  drop_cold: true\n"))
+	require.NoError(t, err)
+	cfg.Dir = filepath.FromSlash("/repo")
+	t.Setenv(ProfileEnv, "")
+	require.Equal(t, filepath.FromSlash("/repo/default.pgo"), cfg.ProfilePath())
+	t.Setenv(ProfileEnv, "cpu.pprof")
+	require.Equal(t, "cpu.pprof", cfg.ProfilePath())
+}
@@ -0,0 +1,278 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// rangeCopyMaxSize is the largest element, in bytes, that a range value
+// variable may copy per iteration without being reported.
+var rangeCopyMaxSize = ruleIntParam("perf_range_value_copy", "max_size", 128)
+
+func init() {
+	rangeCopyAnalyzer.Flags.IntVar(&rangeCopyMaxSize, "max_size", rangeCopyMaxSize,
+		"largest element size in bytes a range value variable may copy")
+}
+
+var rangeCopyAnalyzer = &analysis.Analyzer{
+	Name:     "perf_range_value_copy",
+	Doc:      "reports range loops that copy large elements into their value variable",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_range_value_copy")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_range_value_copy not found")
+		}
+
+		if rangeCopyMaxSize <= 0 {
+			return nil, fmt.Errorf("max_size must be positive, got %d", rangeCopyMaxSize)
+		}
+
+		if pass.TypesSizes == nil {
+			return nil, fmt.Errorf("type sizes unavailable")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		ins.Preorder([]ast.Node{(*ast.RangeStmt)(nil)}, func(node ast.Node) {
+			loop, _ := node.(*ast.RangeStmt)
+			value, ok := loop.Value.(*ast.Ident)
+			if !ok || value.Name == "_" || loop.Tok != token.DEFINE {
+				return
+			}
+			elem := rangeElem(pass.TypesInfo.TypeOf(loop.X))
+			if elem == nil {
+				return
+			}
+			size := typeSize(pass, elem)
+			if size <= int64(rangeCopyMaxSize) {
+				return
+			}
+
+			source := types.ExprString(loop.X)
+			detail := fmt.Sprintf(
+				"range value %s copies %d bytes of %s on every iteration; index %s[i] or take &%s[i]",
+				value.Name, size, types.TypeString(elem, types.RelativeTo(pass.Pkg)), source, source)
+			if fix, ok := rangeIndexFix(pass, loop, value); ok {
+				reportWithFixes(pass, value, rule, detail, fix)
+				return
+			}
+			report(pass, value.Pos(), rule, detail)
+		})
+
+		return nil, nil
+	},
+}
+
+// rangeElem returns the element type of a slice, array, or pointer to array,
+// and nil for anything else.
+func rangeElem(typ types.Type) types.Type {
+	if typ == nil {
+		return nil
+	}
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+		if _, ok := typ.Underlying().(*types.Array); !ok {
+			return nil
+		}
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Slice:
+		return t.Elem()
+	case *types.Array:
+		return t.Elem()
+	}
+	return nil
+}
+
+// rangeIndexFix rewrites `for k, v := range xs` to `for k := range xs` and
+// each use of v in the body to xs[k]. It gives up when the body changes v, k,
+// or xs, takes v's address, or captures v in a closure, since indexing would
+// then observe or modify the shared element instead of a private copy, and
+// when k or xs is shadowed where v is read.
+func rangeIndexFix(pass *analysis.Pass, loop *ast.RangeStmt, value *ast.Ident) (analysis.SuggestedFix, bool) {
+	v, ok := pass.TypesInfo.Defs[value].(*types.Var)
+	if !ok || !isPlainRef(loop.X) {
+		return analysis.SuggestedFix{}, false
+	}
+	source := types.ExprString(loop.X)
+	root := rootIdent(loop.X)
+	xs := pass.TypesInfo.Uses[root]
+	if xs == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	key, _ := loop.Key.(*ast.Ident)
+	var k types.Object
+	if key != nil && key.Name != "_" {
+		k = pass.TypesInfo.Defs[key]
+		if k == nil {
+			return analysis.SuggestedFix{}, false
+		}
+	}
+
+	var refs []*ast.Ident
+	safe := true
+	inspectCopyUses(pass, loop.Body, func(ident *ast.Ident, use copyUse) {
+		switch obj := pass.TypesInfo.Uses[ident]; {
+		case obj == v:
+			if use != copyRead {
+				safe = false
+			}
+			refs = append(refs, ident)
+		case k != nil && obj == k:
+			if use != copyRead {
+				safe = false
+			}
+		case use != copyRead && use != copyCapture && obj == xs:
+			safe = false
+		}
+	})
+	if !safe {
+		return analysis.SuggestedFix{}, false
+	}
+
+	index := "i"
+	if k != nil {
+		index = key.Name
+	} else {
+		index = freshName(pass, loop.Pos(), loop, index)
+	}
+	for _, ref := range refs {
+		if !nameResolvesTo(pass, ref.Pos(), root.Name, xs) || (k != nil && !nameResolvesTo(pass, ref.Pos(), index, k)) {
+			return analysis.SuggestedFix{}, false
+		}
+	}
+
+	edits := []analysis.TextEdit{
+		{Pos: loop.Key.Pos(), End: loop.X.Pos(), NewText: []byte(index + " := range ")},
+	}
+	for _, ref := range refs {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     ref.Pos(),
+			End:     ref.End(),
+			NewText: []byte(fmt.Sprintf("%s[%s]", source, index)),
+		})
+	}
+	return analysis.SuggestedFix{
+		Message:   fmt.Sprintf("Index %s[%s] instead of copying into %s", source, index, value.Name),
+		TextEdits: edits,
+	}, true
+}
+
+// copyUse classifies how an identifier is used.
+type copyUse int
+
+const (
+	copyRead copyUse = iota
+	copyWrite
+	copyAddress
+	copyCapture
+)
+
+// inspectCopyUses calls visit for every identifier in body that roots an
+// expression, classifying the use: assigned to or through, addressed
+// (including by slicing an array or calling a pointer method on it), captured
+// by a function literal, or only read.
+func inspectCopyUses(pass *analysis.Pass, body *ast.BlockStmt, visit func(*ast.Ident, copyUse)) {
+	marked := make(map[*ast.Ident]copyUse)
+	mark := func(expr ast.Expr, use copyUse) {
+		if ident := rootIdent(expr); ident != nil {
+			if _, seen := marked[ident]; !seen {
+				marked[ident] = use
+			}
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			ast.Inspect(n.Body, func(inner ast.Node) bool {
+				if ident, ok := inner.(*ast.Ident); ok {
+					marked[ident] = copyCapture
+				}
+				return true
+			})
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				mark(lhs, copyWrite)
+			}
+		case *ast.IncDecStmt:
+			mark(n.X, copyWrite)
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				mark(n.Key, copyWrite)
+				mark(n.Value, copyWrite)
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				mark(n.X, copyAddress)
+			}
+		case *ast.SliceExpr:
+			if typ := pass.TypesInfo.TypeOf(n.X); typ != nil {
+				if _, ok := typ.Underlying().(*types.Array); ok {
+					mark(n.X, copyAddress)
+				}
+			}
+		case *ast.SelectorExpr:
+			sel := pass.TypesInfo.Selections[n]
+			if sel == nil || sel.Kind() != types.MethodVal {
+				break
+			}
+			if _, ptrRecv := sel.Obj().Type().(*types.Signature).Recv().Type().(*types.Pointer); ptrRecv &&
+				!isPointer(pass.TypesInfo.TypeOf(n.X)) {
+				mark(n.X, copyAddress)
+			}
+		case *ast.Ident:
+			use, seen := marked[n]
+			if !seen {
+				use = copyRead
+			}
+			visit(n, use)
+		}
+		return true
+	})
+}
+
+// rootIdent returns the variable an lvalue such as v.f[i].g is rooted in, or
+// nil when it is rooted in something else, like a call result.
+func rootIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.SliceExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// rootName returns the name of the variable expr is rooted in, or "".
+func isPointer(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	_, ok := typ.Underlying().(*types.Pointer)
+	return ok
+}
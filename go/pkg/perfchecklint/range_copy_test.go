@@ -0,0 +1,238 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeCopyIndexesLargeElements(t *testing.T) {
+	src := `package sample
+
+type record struct {
+	id      int
+	payload [64]byte
+	scores  [16]float64
+}
+
+func total(records []record) float64 {
+	var sum float64
+	for i, r := range records {
+		sum += r.scores[i%16] + float64(r.id)
+	}
+	return sum
+}
+
+func ids(records *[4]record) []int {
+	var out []int
+	for _, r := range records {
+		out = append(out, r.id)
+	}
+	return out
+}
+`
+	want := `package sample
+
+type record struct {
+	id      int
+	payload [64]byte
+	scores  [16]float64
+}
+
+func total(records []record) float64 {
+	var sum float64
+	for i := range records {
+		sum += records[i].scores[i%16] + float64(records[i].id)
+	}
+	return sum
+}
+
+func ids(records *[4]record) []int {
+	var out []int
+	for _, r := range records {
+		out = append(out, r.id)
+	}
+	return out
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, rangeCopyAnalyzer, "records.go", src)
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0].Message, "[perf_range_value_copy] range value r copies 200 bytes of record "+
+		"on every iteration; index records[i] or take &records[i]")
+	require.Equal(t, want, applySuggestedFix(t, fset, src, diags[0]))
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[1]),
+		"\tfor i := range records {\n\t\tout = append(out, records[i].id)\n")
+}
+
+func TestRangeCopyPicksFreshIndexName(t *testing.T) {
+	src := `package sample
+
+type frame [256]byte
+
+func checksum(frames []frame, i int) int {
+	sum := i
+	for _, f := range frames {
+		sum += int(f[0])
+	}
+	return sum
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, rangeCopyAnalyzer, "frames.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[0]),
+		"\tfor i2 := range frames {\n\t\tsum += int(frames[i2][0])\n")
+}
+
+func TestRangeCopyReportsWithoutFixWhenCopyMatters(t *testing.T) {
+	src := `package sample
+
+type block struct {
+	data [32]int64
+}
+
+func (b *block) reset() { b.data = [32]int64{} }
+
+func mutated(blocks []block) {
+	for _, b := range blocks {
+		b.data[0]++
+		_ = b
+	}
+}
+
+func addressed(blocks []block, sink func(*block)) {
+	for _, b := range blocks {
+		sink(&b)
+	}
+}
+
+func pointerMethod(blocks []block) {
+	for _, b := range blocks {
+		b.reset()
+	}
+}
+
+func captured(blocks []block) []func() int64 {
+	var fns []func() int64
+	for _, b := range blocks {
+		fns = append(fns, func() int64 { return b.data[0] })
+	}
+	return fns
+}
+
+func reassigned(blocks []block) int64 {
+	var sum int64
+	for _, b := range blocks {
+		blocks = blocks[:0]
+		sum += b.data[0]
+	}
+	return sum
+}
+
+func keyShadowed(blocks []block) int64 {
+	var sum int64
+	for i, b := range blocks {
+		for i := range 3 {
+			sum += b.data[i]
+		}
+		_ = i
+	}
+	return sum
+}
+
+func keyWritten(blocks []block) int64 {
+	var sum int64
+	for i, b := range blocks {
+		i++
+		sum += b.data[i%32]
+	}
+	return sum
+}
+
+func sourceShadowed(blocks []block) int64 {
+	var sum int64
+	for _, b := range blocks {
+		blocks := [][32]int64{}
+		sum += b.data[len(blocks)]
+	}
+	return sum
+}
+`
+
+	diags := runAnalyzerOnSource(t, rangeCopyAnalyzer, "blocks.go", src)
+	require.Len(t, diags, 8)
+	for _, diag := range diags {
+		require.Empty(t, diag.SuggestedFixes, diag.Message)
+	}
+}
+
+func TestRangeCopyIgnoresSmallElementsAndOtherRanges(t *testing.T) {
+	src := `package sample
+
+type point struct{ x, y, z float64 }
+
+type wide struct{ data [32]int64 }
+
+func walk(points []point, wides []wide, byName map[string]wide, ws []wide) float64 {
+	var sum float64
+	for _, p := range points {
+		sum += p.x
+	}
+	for _, w := range byName {
+		sum += float64(w.data[0])
+	}
+	for i := range wides {
+		sum += float64(wides[i].data[0])
+	}
+	var w wide
+	for _, w = range ws {
+	}
+	_ = w
+	return sum
+}
+`
+
+	diags := runAnalyzerOnSource(t, rangeCopyAnalyzer, "walk.go", src)
+	require.Empty(t, diags)
+}
+
+func TestRangeCopyHonorsMaxSizeFlag(t *testing.T) {
+	setAnalyzerFlag(t, rangeCopyAnalyzer, "max_size", "16")
+
+	src := `package sample
+
+type point struct{ x, y, z float64 }
+
+func sum(points []point) float64 {
+	var total float64
+	for _, p := range points {
+		total += p.x + p.y + p.z
+	}
+	return total
+}
+`
+
+	diags := runAnalyzerOnSource(t, rangeCopyAnalyzer, "sum.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "range value p copies 24 bytes of point")
+}
+
+func TestRangeCopyKeepsCopyWhenArrayValueIsSliced(t *testing.T) {
+	src := `package sample
+
+func reset(rows [][32]int64) int64 {
+	var sum int64
+	for _, row := range rows {
+		s := row[:]
+		s[0] = 0
+		sum += row[1]
+	}
+	return sum
+}
+`
+
+	diags := runAnalyzerOnSource(t, rangeCopyAnalyzer, "rows.go", src)
+	require.Len(t, diags, 1)
+	require.Empty(t, diags[0].SuggestedFixes)
+}
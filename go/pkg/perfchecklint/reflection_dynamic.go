@@ -12,9 +12,11 @@ import (
 )
 
 var reflectionLoopAnalyzer = &analysis.Analyzer{
-	Name:     "perf_reflection_dynamic_loop",
-	Doc:      "reports reflection usage inside hot loops",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Name: "perf_reflection_dynamic_loop",
+	Doc:  "reports reflection usage inside hot loops",
+	Requires: []*analysis.Analyzer{
+		inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer, callCostAnalyzer,
+	},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_avoid_reflection_dynamic")
 		if !ok {
@@ -44,6 +46,8 @@ var reflectionLoopAnalyzer = &analysis.Analyzer{
 			checkReflectionBody(pass, body, rule)
 		})
 
+		reportCostlyCalls(pass, ins, costReflect, rule)
+
 		return nil, nil
 	},
 }
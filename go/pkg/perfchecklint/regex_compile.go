@@ -13,9 +13,11 @@ import (
 )
 
 var regexCompileLoopAnalyzer = &analysis.Analyzer{
-	Name:     "perf_regex_compile_loop",
-	Doc:      "reports regexp compilation executed inside loops",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Name: "perf_regex_compile_loop",
+	Doc:  "reports regexp compilation executed inside loops",
+	Requires: []*analysis.Analyzer{
+		inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer, callCostAnalyzer,
+	},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_regex_compile_once")
 		if !ok {
@@ -45,18 +47,13 @@ var regexCompileLoopAnalyzer = &analysis.Analyzer{
 			checkRegexCalls(pass, body, rule)
 		})
 
+		reportCostlyCalls(pass, ins, costRegexp, rule)
+
 		return nil, nil
 	},
 }
 
 func checkRegexCalls(pass *analysis.Pass, body *ast.BlockStmt, rule ruleset.Rule) {
-	targets := map[string]struct{}{
-		"Compile":          {},
-		"MustCompile":      {},
-		"CompilePOSIX":     {},
-		"MustCompilePOSIX": {},
-	}
-
 	ast.Inspect(body, func(n ast.Node) bool {
 		call, ok := n.(*ast.CallExpr)
 		if !ok {
@@ -68,23 +65,12 @@ func checkRegexCalls(pass *analysis.Pass, body *ast.BlockStmt, rule ruleset.Rule
 			return true
 		}
 
-		obj := pass.TypesInfo.Uses[sel.Sel]
-		fn, ok := obj.(*types.Func)
-		if !ok || fn.Pkg() == nil {
-			return true
-		}
-		if fn.Pkg().Path() != "regexp" {
-			return true
-		}
-		if _, match := targets[fn.Name()]; !match {
+		fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+		if !ok || !isRegexpCompile(fn) {
 			return true
 		}
 
-		pass.Report(analysis.Diagnostic{
-			Pos:      call.Pos(),
-			Message:  fmt.Sprintf("[%s] compile regexp outside loops to avoid repeated parsing", rule.ID),
-			Category: rule.Category,
-		})
+		report(pass, call.Pos(), rule, "compile regexp outside loops to avoid repeated parsing")
 		return true
 	})
 }
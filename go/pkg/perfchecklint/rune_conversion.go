@@ -15,7 +15,7 @@ import (
 var runeConversionAnalyzer = &analysis.Analyzer{
 	Name:     "perf_avoid_rune_conversion",
 	Doc:      "reports []rune conversions used only for ranging",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_avoid_rune_conversion")
 		if !ok {
@@ -0,0 +1,106 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// sqlQueryMethods lists the query methods shared by *sql.DB, *sql.Tx, and
+// *sql.Conn that take the query text as their first non-context argument.
+var sqlQueryMethods = []string{
+	"Exec", "ExecContext", "Prepare", "PrepareContext", "Query", "QueryContext", "QueryRow", "QueryRowContext",
+}
+
+var sqlLoopAnalyzer = &analysis.Analyzer{
+	Name:     "perf_avoid_n_plus_one_query",
+	Doc:      "reports database/sql queries with a constant query string run inside loops",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_avoid_n_plus_one_query")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_avoid_n_plus_one_query not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+			if !push || innermostLoop(stack) == nil {
+				return true
+			}
+			call, _ := node.(*ast.CallExpr)
+			method, ok := sqlQueryMethod(pass, call)
+			if !ok {
+				return true
+			}
+			name := types.ExprString(call.Fun)
+			if strings.HasPrefix(method, "Prepare") {
+				report(pass, call.Pos(), rule, fmt.Sprintf(
+					"%s prepares the same statement on every iteration; prepare it once before the loop "+
+						"and reuse the *sql.Stmt", name))
+				return true
+			}
+			report(pass, call.Pos(), rule, fmt.Sprintf(
+				"%s runs the same query once per iteration (N+1 queries); prepare the statement before the loop, "+
+					"or batch the iterations into one IN (...) or COPY query", name))
+			return true
+		})
+
+		return nil, nil
+	},
+}
+
+// sqlQueryMethod returns the name of the *sql.DB, *sql.Tx, or *sql.Conn query
+// method call invokes, including through an embedded field, when its query
+// argument is a constant string.
+func sqlQueryMethod(pass *analysis.Pass, call *ast.CallExpr) (string, bool) {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "database/sql" {
+		return "", false
+	}
+	recv := fn.Signature().Recv()
+	if recv == nil {
+		return "", false
+	}
+	ptr, ok := recv.Type().(*types.Pointer)
+	if !ok {
+		return "", false
+	}
+	named, ok := types.Unalias(ptr.Elem()).(*types.Named)
+	if !ok {
+		return "", false
+	}
+	if !slices.Contains([]string{"DB", "Tx", "Conn"}, named.Obj().Name()) {
+		return "", false
+	}
+	method := fn.Name()
+	if !slices.Contains(sqlQueryMethods, method) {
+		return "", false
+	}
+
+	queryArg := 0
+	if strings.HasSuffix(method, "Context") {
+		queryArg = 1
+	}
+	if len(call.Args) <= queryArg {
+		return "", false
+	}
+	tv := pass.TypesInfo.Types[call.Args[queryArg]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return method, true
+}
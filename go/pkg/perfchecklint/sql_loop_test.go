@@ -0,0 +1,97 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLLoopReportsQueriesPerIteration(t *testing.T) {
+	src := `package sample
+
+import (
+	"context"
+	"database/sql"
+)
+
+type repo struct {
+	*sql.DB
+}
+
+func sync(ctx context.Context, db *sql.DB, conn *sql.Conn, r repo, ids []int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		var name string
+		if err := db.QueryRow("SELECT name FROM users WHERE id = $1", id).Scan(&name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE users SET seen = true WHERE id = $1", id); err != nil {
+			return err
+		}
+		rows, err := conn.QueryContext(ctx, "SELECT tag FROM tags WHERE user_id = $1", id)
+		if err != nil {
+			return err
+		}
+		rows.Close()
+		stmt, err := r.Prepare("DELETE FROM sessions WHERE user_id = $1")
+		if err != nil {
+			return err
+		}
+		stmt.Close()
+	}
+	return tx.Commit()
+}
+`
+
+	diags := runAnalyzerOnSource(t, sqlLoopAnalyzer, "sync.go", src)
+	require.Len(t, diags, 4)
+	require.Contains(t, diags[0].Message, "[perf_avoid_n_plus_one_query] db.QueryRow runs the same query once per "+
+		"iteration (N+1 queries); prepare the statement before the loop, or batch the iterations into one IN (...) "+
+		"or COPY query")
+	require.Contains(t, diags[1].Message, "tx.ExecContext runs the same query once per iteration")
+	require.Contains(t, diags[2].Message, "conn.QueryContext runs the same query once per iteration")
+	require.Contains(t, diags[3].Message, "r.Prepare prepares the same statement on every iteration; prepare it "+
+		"once before the loop and reuse the *sql.Stmt")
+}
+
+func TestSQLLoopIgnoresStatementsDynamicQueriesAndOtherTypes(t *testing.T) {
+	src := `package sample
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type cache struct{}
+
+func (cache) QueryRow(query string, args ...any) *sql.Row { return nil }
+
+func load(db *sql.DB, c cache, tables []string, ids []int64) error {
+	stmt, err := db.Prepare("SELECT name FROM users WHERE id = $1")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range ids {
+		var name string
+		if err := stmt.QueryRow(id).Scan(&name); err != nil {
+			return err
+		}
+		_ = c.QueryRow("SELECT name FROM users WHERE id = $1", id)
+	}
+	for _, table := range tables {
+		if _, err := db.Exec(fmt.Sprintf("TRUNCATE %s", table)); err != nil {
+			return err
+		}
+	}
+	_, err = db.Exec("VACUUM")
+	return err
+}
+`
+
+	diags := runAnalyzerOnSource(t, sqlLoopAnalyzer, "load.go", src)
+	require.Empty(t, diags)
+}
@@ -13,18 +13,41 @@ import (
 	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
 )
 
-const smallStackThreshold = 32
+// stackAllocMaxSize is the largest value, in bytes, reported as a stack
+// allocation candidate.
+var stackAllocMaxSize = ruleIntParam("perf_prefer_stack_alloc", "max_size", 32)
+
+// stackAllocEscapeLog and stackAllocEscapeAnalysis switch the analyzer from
+// its syntactic heuristic to the compiler's escape analysis, read from a saved
+// `go build -gcflags=-m` log or computed by compiling each package.
+var (
+	stackAllocEscapeLog      string
+	stackAllocEscapeAnalysis bool
+)
+
+func init() {
+	stackAllocAnalyzer.Flags.IntVar(&stackAllocMaxSize, "max_size", stackAllocMaxSize,
+		"largest value size in bytes reported as a stack allocation candidate")
+	stackAllocAnalyzer.Flags.StringVar(&stackAllocEscapeLog, "escape_log", "",
+		"report only allocations that this `go build -gcflags=-m` output moves to the heap")
+	stackAllocAnalyzer.Flags.BoolVar(&stackAllocEscapeAnalysis, "escape_analysis", false,
+		"compile each package with -gcflags=-m=2 and report only allocations that escape to the heap")
+}
 
 var stackAllocAnalyzer = &analysis.Analyzer{
 	Name:     "perf_prefer_stack_alloc",
 	Doc:      "reports heap allocations of tiny structs/values",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_prefer_stack_alloc")
 		if !ok {
 			return nil, fmt.Errorf("rule perf_prefer_stack_alloc not found")
 		}
 
+		if stackAllocMaxSize <= 0 {
+			return nil, fmt.Errorf("max_size must be positive, got %d", stackAllocMaxSize)
+		}
+
 		if pass.TypesSizes == nil {
 			return nil, fmt.Errorf("type sizes unavailable")
 		}
@@ -34,6 +57,19 @@ var stackAllocAnalyzer = &analysis.Analyzer{
 			return nil, fmt.Errorf("missing inspector dependency")
 		}
 
+		var escapes escapeDecisions
+		var err error
+		switch {
+		case stackAllocEscapeAnalysis:
+			escapes, err = compilerEscapeDecisions(pass)
+		case stackAllocEscapeLog != "":
+			escapes, err = cachedEscapeLog(stackAllocEscapeLog)
+		}
+		if err != nil {
+			return nil, err
+		}
+		check := &stackAllocCheck{pass: pass, rule: rule, escapes: escapes, moved: make(map[types.Object]bool)}
+
 		nodeFilter := []ast.Node{(*ast.UnaryExpr)(nil), (*ast.CallExpr)(nil)}
 		ins.Preorder(nodeFilter, func(node ast.Node) {
 			switch v := node.(type) {
@@ -41,12 +77,12 @@ var stackAllocAnalyzer = &analysis.Analyzer{
 				if v.Op != token.AND {
 					return
 				}
-				reportSmallComposite(pass, v, v.X, rule)
+				check.address(v)
 			case *ast.CallExpr:
 				if ident, ok := v.Fun.(*ast.Ident); !ok || ident.Name != "new" {
 					return
 				}
-				reportSmallNew(pass, v, rule)
+				check.newCall(v)
 			}
 		})
 
@@ -54,40 +90,96 @@ var stackAllocAnalyzer = &analysis.Analyzer{
 	},
 }
 
-func reportSmallComposite(pass *analysis.Pass, unary *ast.UnaryExpr, expr ast.Expr, rule ruleset.Rule) {
-	typ := pass.TypesInfo.TypeOf(expr)
-	if typ == nil {
+// stackAllocCheck reports small heap allocations. Without escape decisions
+// every &x, &T{...}, and new(T) of a small type is reported; with them only the
+// allocations the compiler moves to the heap are, including variables whose
+// address escapes.
+type stackAllocCheck struct {
+	pass    *analysis.Pass
+	rule    ruleset.Rule
+	escapes escapeDecisions
+	moved   map[types.Object]bool
+}
+
+func (c *stackAllocCheck) address(unary *ast.UnaryExpr) {
+	x := ast.Unparen(unary.X)
+	if _, ok := x.(*ast.CompositeLit); ok || c.escapes == nil {
+		typ := c.pass.TypesInfo.TypeOf(x)
+		size, ok := c.smallSize(typ)
+		if !ok {
+			return
+		}
+		c.report(unary.OpPos, unary.OpPos, fmt.Sprintf("%s is %dB", types.TypeString(typ, nil), size),
+			"prefer stack allocation")
 		return
 	}
-	if !isSmallStackCandidate(typ) {
+	// Taking an address allocates only when the compiler moves the variable
+	// to the heap, which it reports at the declaration.
+	ident, ok := x.(*ast.Ident)
+	if !ok {
+		return
+	}
+	obj, ok := c.pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok || c.moved[obj] || obj.Pkg() != c.pass.Pkg {
+		return
+	}
+	size, ok := c.smallSize(obj.Type())
+	if !ok {
 		return
 	}
-	size := pass.TypesSizes.Sizeof(typ)
-	if size <= 0 || size > smallStackThreshold {
+	if _, escaped := c.escapes.lookup(c.pass.Fset, obj.Pos()); !escaped {
 		return
 	}
-	msg := fmt.Sprintf("%s is %dB; prefer stack allocation", types.TypeString(typ, nil), size)
-	report(pass, unary.OpPos, rule, msg)
+	c.moved[obj] = true
+	c.report(unary.OpPos, obj.Pos(), fmt.Sprintf("taking the address of %s (%s, %dB) moves it to the heap",
+		obj.Name(), types.TypeString(obj.Type(), nil), size), "keep it local or pass it by value")
 }
 
-func reportSmallNew(pass *analysis.Pass, call *ast.CallExpr, rule ruleset.Rule) {
+func (c *stackAllocCheck) newCall(call *ast.CallExpr) {
 	if len(call.Args) != 1 {
 		return
 	}
-	typ := pass.TypesInfo.TypeOf(call)
-	ptr, ok := typ.(*types.Pointer)
+	ptr, ok := c.pass.TypesInfo.TypeOf(call).(*types.Pointer)
 	if !ok {
 		return
 	}
-	if !isSmallStackCandidate(ptr.Elem()) {
+	size, ok := c.smallSize(ptr.Elem())
+	if !ok {
+		return
+	}
+	c.report(call.Lparen, call.Lparen, fmt.Sprintf("new(%s) allocates %dB on heap",
+		types.TypeString(ptr.Elem(), nil), size), "store it by value")
+}
+
+// report emits a finding at pos. With escape decisions the allocation at site
+// must have escaped, and the compiler's reason joins the message.
+func (c *stackAllocCheck) report(pos, site token.Pos, what, advice string) {
+	if c.escapes == nil {
+		report(c.pass, pos, c.rule, what+"; "+advice)
 		return
 	}
-	size := pass.TypesSizes.Sizeof(ptr.Elem())
-	if size <= 0 || size > smallStackThreshold {
+	reason, escaped := c.escapes.lookup(c.pass.Fset, site)
+	if !escaped {
 		return
 	}
-	msg := fmt.Sprintf("new(%s) allocates %dB on heap; store it by value", types.TypeString(ptr.Elem(), nil), size)
-	report(pass, call.Lparen, rule, msg)
+	detail := what + "; compiler: escapes to heap"
+	if reason != "" {
+		detail += " via " + reason
+	}
+	report(c.pass, pos, c.rule, detail+"; "+advice)
+}
+
+// smallSize returns the size of typ when it is a stack allocation candidate
+// no larger than max_size.
+func (c *stackAllocCheck) smallSize(typ types.Type) (int64, bool) {
+	if !isSmallStackCandidate(typ) {
+		return 0, false
+	}
+	size := typeSize(c.pass, typ)
+	if size <= 0 || size > int64(stackAllocMaxSize) {
+		return 0, false
+	}
+	return size, true
 }
 
 func isSmallStackCandidate(typ types.Type) bool {
@@ -103,3 +195,30 @@ func isSmallStackCandidate(typ types.Type) bool {
 	}
 	return false
 }
+
+// typeSize returns the size of typ, or -1 when it depends on type parameters:
+// go/types panics when asked to size those.
+func typeSize(pass *analysis.Pass, typ types.Type) int64 {
+	if dependsOnTypeParams(typ) {
+		return -1
+	}
+	return pass.TypesSizes.Sizeof(typ)
+}
+
+func dependsOnTypeParams(typ types.Type) bool {
+	switch t := types.Unalias(typ).(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Named:
+		return dependsOnTypeParams(t.Underlying())
+	case *types.Array:
+		return dependsOnTypeParams(t.Elem())
+	case *types.Struct:
+		for field := range t.Fields() {
+			if dependsOnTypeParams(field.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
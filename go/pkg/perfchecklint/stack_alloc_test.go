@@ -1,6 +1,13 @@
 package perfchecklint
 
-import "testing"
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
 
 func TestStackAllocAnalyzerFlagsSmallStructPointer(t *testing.T) {
 	src := `package sample
@@ -23,6 +30,24 @@ func newPoint(x, y int) *point {
 	}
 }
 
+func TestStackAllocAnalyzerFlagsAddressOfSmallVariable(t *testing.T) {
+	src := `package sample
+
+type point struct {
+	x, y int
+}
+
+func origin() *point {
+	var p point
+	return &p
+}
+`
+
+	diags := runAnalyzerOnSource(t, stackAllocAnalyzer, "stack_alloc_addr.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "[perf_prefer_stack_alloc] sample.point is 16B; prefer stack allocation")
+}
+
 func TestStackAllocAnalyzerIgnoresLargeStruct(t *testing.T) {
 	src := `package sample
 
@@ -40,3 +65,131 @@ func makeLarge() *large {
 		t.Fatalf("expected no diagnostics, got %d", len(diags))
 	}
 }
+
+func TestStackAllocAnalyzerHonorsMaxSizeFlag(t *testing.T) {
+	if got := stackAllocAnalyzer.Flags.Lookup("max_size").DefValue; got != "32" {
+		t.Fatalf("expected registry default max_size 32, got %s", got)
+	}
+
+	src := `package sample
+
+type pair struct {
+	a, b [3]int64
+}
+
+func newPair() *pair {
+	return &pair{}
+}
+`
+
+	if diags := runAnalyzerOnSource(t, stackAllocAnalyzer, "stack_alloc_flag.go", src); len(diags) != 0 {
+		t.Fatalf("expected 48B struct to pass the default threshold, got %d diagnostics", len(diags))
+	}
+
+	setAnalyzerFlag(t, stackAllocAnalyzer, "max_size", "64")
+	diags := runAnalyzerOnSource(t, stackAllocAnalyzer, "stack_alloc_flag.go", src)
+	if len(diags) != 1 || !containsRule(diags, "perf_prefer_stack_alloc") {
+		t.Fatalf("expected 1 perf_prefer_stack_alloc diagnostic with max_size=64, got %d", len(diags))
+	}
+}
+
+func TestStackAllocAnalyzerSkipsGenericSizes(t *testing.T) {
+	src := `package sample
+
+type node[K comparable, V any] struct {
+	key  K
+	val  V
+	next *node[K, V]
+}
+
+func push[K comparable, V any](head *node[K, V], k K, v V) *node[K, V] {
+	return &node[K, V]{key: k, val: v, next: head}
+}
+
+func ints() *node[int, int] {
+	return &node[int, int]{}
+}
+
+func addr() *int {
+	x := 1
+	return &x
+}
+`
+
+	diags := runAnalyzerOnSource(t, stackAllocAnalyzer, "stack_alloc_generic.go", src)
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0].Message, "sample.node[int, int] is 24B; prefer stack allocation")
+	require.Contains(t, diags[1].Message, "int is 8B; prefer stack allocation")
+}
+
+const escapeSource = `package sample
+
+type point struct{ x, y int }
+
+var sink *point
+
+func newPoint(x, y int) *point {
+	return &point{x: x, y: y}
+}
+
+func local() int {
+	p := &point{1, 2}
+	q := new(point)
+	return p.x + q.y
+}
+
+func global() {
+	v := point{}
+	sink = &v
+	sink = &v
+	sink = new(point)
+}
+`
+
+func TestStackAllocAnalyzerUsesEscapeLog(t *testing.T) {
+	dir := t.TempDir()
+	log := `# example.com/sample
+./escape.go:7:6: can inline newPoint
+./escape.go:8:9: &point{...} escapes to heap in newPoint:
+./escape.go:8:9:   flow: ~r0 ← &{storage for &point{...}}:
+./escape.go:8:9:     from &point{...} (spill) at ./escape.go:8:9
+./escape.go:8:9:     from return &point{...} (return) at ./escape.go:8:2
+./escape.go:8:9: &point{...} escapes to heap
+./escape.go:12:7: &point{...} does not escape
+./escape.go:13:10: new(point) does not escape
+./escape.go:18:2: moved to heap: v
+./escape.go:21:12: new(point) escapes to heap
+`
+	logPath := filepath.Join(dir, "escape.log")
+	require.NoError(t, os.WriteFile(logPath, []byte(log), 0o600))
+	setAnalyzerFlag(t, stackAllocAnalyzer, "escape_log", logPath)
+
+	diags := runAnalyzerOnSource(t, stackAllocAnalyzer, filepath.Join(dir, "escape.go"), escapeSource)
+	messages := make([]string, 0, len(diags))
+	for _, diag := range diags {
+		messages = append(messages, diag.Message)
+	}
+	require.Len(t, messages, 3)
+	require.Contains(t, messages[0], "sample.point is 16B; compiler: escapes to heap via "+
+		"return &point{...} (return) at escape.go:8:2; prefer stack allocation")
+	require.Contains(t, messages[1], "taking the address of v (sample.point, 16B) moves it to the heap; "+
+		"compiler: escapes to heap; keep it local or pass it by value")
+	require.Contains(t, messages[2], "new(sample.point) allocates 16B on heap; compiler: escapes to heap; "+
+		"store it by value")
+}
+
+func TestStackAllocAnalyzerRunsEscapeAnalysis(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command unavailable")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/sample\n\ngo 1.22\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "escape.go"), []byte(escapeSource), 0o600))
+	setAnalyzerFlag(t, stackAllocAnalyzer, "escape_analysis", "true")
+
+	diags := runAnalyzerOnSource(t, stackAllocAnalyzer, filepath.Join(dir, "escape.go"), escapeSource)
+	require.Len(t, diags, 3)
+	require.Contains(t, diags[0].Message, "via return &point{...} (return) at escape.go:8:2")
+	require.Contains(t, diags[1].Message, "taking the address of v")
+	require.Contains(t, diags[2].Message, "via sink = new(point) (assign) at escape.go:21:7")
+}
@@ -5,6 +5,7 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"strings"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
@@ -16,7 +17,7 @@ import (
 var stringConcatLoopAnalyzer = &analysis.Analyzer{
 	Name:     "perf_string_concat_loop",
 	Doc:      "reports string concatenation inside loops",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_avoid_string_concat_loop")
 		if !ok {
@@ -30,64 +31,358 @@ var stringConcatLoopAnalyzer = &analysis.Analyzer{
 
 		nodeFilter := []ast.Node{(*ast.ForStmt)(nil), (*ast.RangeStmt)(nil)}
 
-		ins.Preorder(nodeFilter, func(node ast.Node) {
-			var body *ast.BlockStmt
-			switch stmt := node.(type) {
-			case *ast.ForStmt:
-				body = stmt.Body
-			case *ast.RangeStmt:
-				body = stmt.Body
-			default:
-				return
+		// Only outermost loops are checked: nested loops are covered by the
+		// enclosing body walk, and the builder fix is hoisted as far out as
+		// the concatenated variable allows.
+		ins.Nodes(nodeFilter, func(node ast.Node, push bool) bool {
+			if !push {
+				return false
 			}
-			if body == nil {
-				return
+			loop, ok := node.(ast.Stmt)
+			if !ok || loopBody(loop) == nil {
+				return true
 			}
-			checkConcatBody(pass, body, rule)
+			checkConcatBody(pass, loop, rule)
+			return false
 		})
 
 		return nil, nil
 	},
 }
 
-func checkConcatBody(pass *analysis.Pass, body *ast.BlockStmt, rule ruleset.Rule) {
+// concatSite is a single `s += x` or `s = s + x` statement inside a loop.
+type concatSite struct {
+	assign *ast.AssignStmt
+	piece  ast.Expr
+	inFunc bool
+}
+
+func checkConcatBody(pass *analysis.Pass, loop ast.Stmt, rule ruleset.Rule) {
+	body := loopBody(loop)
+	if body == nil {
+		return
+	}
+
+	var sites []concatSite
+	var stack []ast.Node
 	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+
 		assign, ok := n.(*ast.AssignStmt)
 		if !ok {
 			return true
 		}
+		if piece := concatPiece(pass, assign); piece != nil {
+			sites = append(sites, concatSite{assign: assign, piece: piece, inFunc: insideFuncLit(stack)})
+		}
+		return true
+	})
 
-		switch assign.Tok {
-		case token.ADD_ASSIGN:
-			if len(assign.Lhs) != 1 {
-				return true
-			}
-			if isString(pass.TypesInfo, assign.Lhs[0]) {
-				report(pass, assign.Pos(), rule, "string concatenation using '+=' inside loop")
-			}
-		case token.ASSIGN:
-			if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
-				return true
+	fixes := builderFixes(pass, loop, sites)
+	for _, site := range sites {
+		detail := "string concatenation using '=', consider strings.Builder"
+		if site.assign.Tok == token.ADD_ASSIGN {
+			detail = "string concatenation using '+=' inside loop"
+		}
+		fix, ok := fixes[site.assign]
+		if !ok {
+			report(pass, site.assign.Pos(), rule, detail)
+			continue
+		}
+		reportWithFixes(pass, site.assign, rule, detail, fix)
+	}
+}
+
+// concatPiece returns the appended operand when assign grows a string in
+// place, or nil otherwise.
+func concatPiece(pass *analysis.Pass, assign *ast.AssignStmt) ast.Expr {
+	switch assign.Tok {
+	case token.ADD_ASSIGN:
+		if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return nil
+		}
+		if isString(pass.TypesInfo, assign.Lhs[0]) {
+			return assign.Rhs[0]
+		}
+	case token.ASSIGN:
+		if len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return nil
+		}
+		bin, ok := assign.Rhs[0].(*ast.BinaryExpr)
+		if !ok || bin.Op != token.ADD {
+			return nil
+		}
+		if !isString(pass.TypesInfo, assign.Lhs[0]) {
+			return nil
+		}
+		if exprEqual(assign.Lhs[0], bin.X) {
+			return bin.Y
+		}
+	}
+	return nil
+}
+
+// builderFixes computes one strings.Builder rewrite per concatenated local
+// variable. The fix is attached to the first site of each variable and is only
+// offered when the loop never reads the string, so hoisting the builder keeps
+// the observable behavior unchanged.
+func builderFixes(pass *analysis.Pass, loop ast.Stmt, sites []concatSite) map[*ast.AssignStmt]analysis.SuggestedFix {
+	fixes := make(map[*ast.AssignStmt]analysis.SuggestedFix)
+	if len(sites) == 0 || pass.Pkg == nil || !loopExitsNormally(loop) {
+		return fixes
+	}
+	path := enclosingPath(pass, loop)
+	fnBody := enclosingFunc(path)
+	if fnBody == nil {
+		return fixes
+	}
+
+	byVar := make(map[*types.Var][]concatSite)
+	var order []*types.Var
+	for _, site := range sites {
+		v := concatTarget(pass, loop, site)
+		if v == nil {
+			continue
+		}
+		if _, seen := byVar[v]; !seen {
+			order = append(order, v)
+		}
+		byVar[v] = append(byVar[v], site)
+	}
+
+	for _, v := range order {
+		varSites := byVar[v]
+		if !onlyConcatUses(pass, loop, v, varSites) || capturedOrAddressed(pass, fnBody, v) {
+			continue
+		}
+		fix, ok := builderFix(pass, path, fnBody, loop, v, varSites)
+		if !ok {
+			continue
+		}
+		fixes[varSites[0].assign] = fix
+	}
+	return fixes
+}
+
+// concatTarget returns the local variable grown by site when it is declared
+// outside the loop and the site executes directly in the loop body.
+func concatTarget(pass *analysis.Pass, loop ast.Stmt, site concatSite) *types.Var {
+	if site.inFunc {
+		return nil
+	}
+	ident, ok := site.assign.Lhs[0].(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	v, ok := pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok || v.IsField() || v.Pkg() == nil || v.Parent() == v.Pkg().Scope() {
+		return nil
+	}
+	if loop.Pos() <= v.Pos() && v.Pos() < loop.End() {
+		return nil
+	}
+	return v
+}
+
+// onlyConcatUses reports whether every reference to v inside the loop is the
+// assignment target (or the left operand of `v = v + x`) of one of sites.
+func onlyConcatUses(pass *analysis.Pass, loop ast.Stmt, v *types.Var, sites []concatSite) bool {
+	allowed := make(map[*ast.Ident]bool, 2*len(sites))
+	for _, site := range sites {
+		if ident, ok := site.assign.Lhs[0].(*ast.Ident); ok {
+			allowed[ident] = true
+		}
+		if bin, ok := site.assign.Rhs[0].(*ast.BinaryExpr); ok && site.assign.Tok == token.ASSIGN {
+			if ident, ok := bin.X.(*ast.Ident); ok {
+				allowed[ident] = true
 			}
-			bin, ok := assign.Rhs[0].(*ast.BinaryExpr)
-			if !ok || bin.Op != token.ADD {
-				return true
+		}
+	}
+
+	clean := true
+	ast.Inspect(loop, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || !clean {
+			return clean
+		}
+		if pass.TypesInfo.Uses[ident] == v && !allowed[ident] {
+			clean = false
+		}
+		return clean
+	})
+	return clean
+}
+
+// capturedOrAddressed reports whether v may be observed indirectly through a
+// closure or a pointer, which would make the intermediate values visible.
+func capturedOrAddressed(pass *analysis.Pass, fnBody *ast.BlockStmt, v *types.Var) bool {
+	found := false
+	var funcDepth int
+	var stack []ast.Node
+	ast.Inspect(fnBody, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		if n == nil {
+			if _, ok := stack[len(stack)-1].(*ast.FuncLit); ok {
+				funcDepth--
 			}
-			if !isString(pass.TypesInfo, assign.Lhs[0]) {
-				return true
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+		switch node := n.(type) {
+		case *ast.FuncLit:
+			funcDepth++
+		case *ast.UnaryExpr:
+			if ident, ok := node.X.(*ast.Ident); ok && node.Op == token.AND && pass.TypesInfo.Uses[ident] == v {
+				found = true
 			}
-			if exprEqual(assign.Lhs[0], bin.X) {
-				report(
-					pass,
-					assign.Pos(),
-					rule,
-					"string concatenation using '=', consider strings.Builder",
-				)
+		case *ast.Ident:
+			if funcDepth > 0 && pass.TypesInfo.Uses[node] == v {
+				found = true
 			}
 		}
-
 		return true
 	})
+	return found
+}
+
+// loopExitsNormally reports whether control always leaves the loop through its
+// end, so the value restored after the loop is the one later code observes.
+func loopExitsNormally(loop ast.Stmt) bool {
+	ok := true
+	ast.Inspect(loopBody(loop), func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			ok = false
+		case *ast.BranchStmt:
+			if s.Tok == token.GOTO || s.Label != nil {
+				ok = false
+			}
+		}
+		return ok
+	})
+	return ok
+}
+
+func builderFix(
+	pass *analysis.Pass,
+	path []ast.Node,
+	fnBody *ast.BlockStmt,
+	loop ast.Stmt,
+	v *types.Var,
+	sites []concatSite,
+) (analysis.SuggestedFix, bool) {
+	file := fileFor(pass, loop.Pos())
+	if file == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	anchor := statementAnchor(path, loop)
+	qualifier, edits, ok := importQualifier(pass, file, anchor.Pos(), "strings")
+	if !ok {
+		return analysis.SuggestedFix{}, false
+	}
+
+	builder := freshName(pass, anchor.Pos(), fnBody, v.Name()+"Builder")
+	indent := lineIndent(pass, anchor.Pos())
+
+	var prologue strings.Builder
+	fmt.Fprintf(&prologue, "var %s %s.Builder\n%s", builder, qualifier, indent)
+	if !declaredEmptyBefore(pass, path, anchor, v) {
+		fmt.Fprintf(&prologue, "%s.WriteString(%s)\n%s", builder, v.Name(), indent)
+	}
+	edits = append(edits, analysis.TextEdit{Pos: anchor.Pos(), End: anchor.Pos(), NewText: []byte(prologue.String())})
+
+	for _, site := range sites {
+		edits = append(edits,
+			analysis.TextEdit{Pos: site.assign.Pos(), End: site.piece.Pos(), NewText: []byte(builder + ".WriteString(")},
+			analysis.TextEdit{Pos: site.piece.End(), End: site.assign.End(), NewText: []byte(")")},
+		)
+	}
+
+	epilogue := fmt.Sprintf("\n%s%s = %s.String()", indent, v.Name(), builder)
+	edits = append(edits, analysis.TextEdit{Pos: loop.End(), End: loop.End(), NewText: []byte(epilogue)})
+
+	return analysis.SuggestedFix{
+		Message:   fmt.Sprintf("Accumulate %s in a strings.Builder", v.Name()),
+		TextEdits: edits,
+	}, true
+}
+
+// declaredEmptyBefore reports whether the statement right before anchor
+// declares v with an empty value, making the builder seed redundant.
+func declaredEmptyBefore(pass *analysis.Pass, path []ast.Node, anchor ast.Node, v *types.Var) bool {
+	var list []ast.Stmt
+	for _, node := range path {
+		switch block := node.(type) {
+		case *ast.BlockStmt:
+			list = block.List
+		case *ast.CaseClause:
+			list = block.Body
+		case *ast.CommClause:
+			list = block.Body
+		default:
+			continue
+		}
+		break
+	}
+	var prev ast.Stmt
+	for _, stmt := range list {
+		if stmt == anchor {
+			break
+		}
+		prev = stmt
+	}
+
+	isEmpty := func(expr ast.Expr) bool {
+		tv, ok := pass.TypesInfo.Types[expr]
+		return ok && tv.Value != nil && tv.Value.ExactString() == `""`
+	}
+	switch s := prev.(type) {
+	case *ast.AssignStmt:
+		if s.Tok != token.DEFINE || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+			return false
+		}
+		ident, ok := s.Lhs[0].(*ast.Ident)
+		return ok && pass.TypesInfo.Defs[ident] == v && isEmpty(s.Rhs[0])
+	case *ast.DeclStmt:
+		gen, ok := s.Decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.VAR || len(gen.Specs) != 1 {
+			return false
+		}
+		spec, ok := gen.Specs[0].(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || pass.TypesInfo.Defs[spec.Names[0]] != v {
+			return false
+		}
+		return len(spec.Values) == 0 || isEmpty(spec.Values[0])
+	}
+	return false
+}
+
+func loopBody(loop ast.Stmt) *ast.BlockStmt {
+	switch stmt := loop.(type) {
+	case *ast.ForStmt:
+		return stmt.Body
+	case *ast.RangeStmt:
+		return stmt.Body
+	}
+	return nil
+}
+
+func insideFuncLit(stack []ast.Node) bool {
+	for _, node := range stack {
+		if _, ok := node.(*ast.FuncLit); ok {
+			return true
+		}
+	}
+	return false
 }
 
 func isString(info *types.Info, expr ast.Expr) bool {
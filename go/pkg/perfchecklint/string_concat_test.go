@@ -52,7 +52,145 @@ func build(items []string) string {
 	require.Empty(t, diags)
 }
 
+func TestStringConcatLoopAnalyzerSuggestsBuilder(t *testing.T) {
+	src := `package sample
+
+import "strings"
+
+func build(items []string) string {
+	s := ""
+	for _, item := range items {
+		s += strings.TrimSpace(item)
+	}
+	return s
+}
+`
+	want := `package sample
+
+import "strings"
+
+func build(items []string) string {
+	s := ""
+	var sBuilder strings.Builder
+	for _, item := range items {
+		sBuilder.WriteString(strings.TrimSpace(item))
+	}
+	s = sBuilder.String()
+	return s
+}
+`
+
+	fset, diags := runConcatWithFileSet(src)
+	require.Len(t, diags, 1)
+	require.Equal(t, want, applySuggestedFix(t, fset, src, diags[0]))
+}
+
+func TestStringConcatLoopAnalyzerSeedsBuilderAndAddsImport(t *testing.T) {
+	src := `package sample
+
+func build(prefix string, items []string) string {
+	out := prefix
+	for i := 0; i < len(items); i++ {
+		for _, part := range items[i:] {
+			out = out + part
+		}
+		out += ","
+	}
+	return out
+}
+`
+	want := `package sample
+
+import "strings"
+
+func build(prefix string, items []string) string {
+	out := prefix
+	var outBuilder strings.Builder
+	outBuilder.WriteString(out)
+	for i := 0; i < len(items); i++ {
+		for _, part := range items[i:] {
+			outBuilder.WriteString(part)
+		}
+		outBuilder.WriteString(",")
+	}
+	out = outBuilder.String()
+	return out
+}
+`
+
+	fset, diags := runConcatWithFileSet(src)
+	require.Len(t, diags, 2)
+	require.Len(t, diags[0].SuggestedFixes, 1)
+	require.Empty(t, diags[1].SuggestedFixes)
+	require.Equal(t, want, applySuggestedFix(t, fset, src, diags[0]))
+}
+
+func TestStringConcatLoopAnalyzerSkipsFixWhenValueObserved(t *testing.T) {
+	cases := map[string]string{
+		"read inside loop": `package sample
+
+func build(items []string) string {
+	s := ""
+	for _, item := range items {
+		if len(s) > 80 {
+			break
+		}
+		s += item
+	}
+	return s
+}
+`,
+		"early return": `package sample
+
+func build(items []string) string {
+	s := ""
+	for _, item := range items {
+		if item == "" {
+			return s
+		}
+		s += item
+	}
+	return s
+}
+`,
+		"captured by closure": `package sample
+
+func build(items []string, sink func(func() string)) string {
+	s := ""
+	sink(func() string { return s })
+	for _, item := range items {
+		s += item
+	}
+	return s
+}
+`,
+		"declared inside loop": `package sample
+
+func build(items []string) {
+	for _, item := range items {
+		s := "> "
+		s += item
+		_ = s
+	}
+}
+`,
+	}
+
+	for name, src := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, diags := runConcatWithFileSet(src)
+			require.Len(t, diags, 1)
+			require.Empty(t, diags[0].SuggestedFixes)
+		})
+	}
+}
+
 func runConcat(src string) []analysis.Diagnostic {
+	_, diags := runConcatWithFileSet(src)
+	return diags
+}
+
+func runConcatWithFileSet(src string) (*token.FileSet, []analysis.Diagnostic) {
 	fset := token.NewFileSet()
 	file, err := parser.ParseFile(fset, "concat.go", src, parser.ParseComments)
 	if err != nil {
@@ -68,7 +206,8 @@ func runConcat(src string) []analysis.Diagnostic {
 	}
 
 	conf := types.Config{Importer: importer.Default()}
-	if _, err := conf.Check("sample", fset, []*ast.File{file}, info); err != nil {
+	pkg, err := conf.Check("sample", fset, []*ast.File{file}, info)
+	if err != nil {
 		panic(err)
 	}
 
@@ -79,23 +218,29 @@ func runConcat(src string) []analysis.Diagnostic {
 	}
 	pass := analysis.Pass{
 		Fset:      fset,
+		Files:     []*ast.File{file},
+		Pkg:       pkg,
 		TypesInfo: info,
 		Report: func(d analysis.Diagnostic) {
 			diags = append(diags, d)
 		},
 	}
-	var fnBody *ast.BlockStmt
-	for _, decl := range file.Decls {
-		if fn, ok := decl.(*ast.FuncDecl); ok {
-			fnBody = fn.Body
-			break
+	var loop ast.Stmt
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch stmt := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			if loop == nil {
+				loop = stmt.(ast.Stmt)
+			}
+			return false
 		}
+		return loop == nil
+	})
+	if loop == nil {
+		panic("no loop found")
 	}
-	if fnBody == nil {
-		panic("no function body found")
-	}
-	checkConcatBody(&pass, fnBody, rule)
-	return diags
+	checkConcatBody(&pass, loop, rule)
+	return fset, diags
 }
 
 func containsRule(diags []analysis.Diagnostic, id string) bool {
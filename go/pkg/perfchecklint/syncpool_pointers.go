@@ -15,7 +15,7 @@ import (
 var syncPoolPointerAnalyzer = &analysis.Analyzer{
 	Name:     "perf_syncpool_store_pointers",
 	Doc:      "reports storing non-pointer values in sync.Pool",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_syncpool_store_pointers")
 		if !ok {
@@ -0,0 +1,283 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+// syscallLoopMaxBuffer is the largest buffer, in bytes, for which a direct
+// os.File read or write inside a loop counts as a small syscall.
+var syscallLoopMaxBuffer = ruleIntParam("perf_batch_syscalls_cgo", "max_buffer", 512)
+
+func init() {
+	syscallLoopAnalyzer.Flags.IntVar(&syscallLoopMaxBuffer, "max_buffer", syscallLoopMaxBuffer,
+		"largest os.File read/write buffer in bytes treated as a small syscall")
+}
+
+// boundaryClass describes the fixed cost of one crossing into C or the
+// kernel, independent of the work done on the other side.
+type boundaryClass struct {
+	into string
+	name string
+	cost string
+}
+
+var (
+	cgoBoundary     = boundaryClass{into: "C", name: "cgo", cost: "~100ns per call"}
+	syscallBoundary = boundaryClass{into: "the kernel", name: "syscall", cost: "~0.5-1µs per call"}
+	fileBoundary    = boundaryClass{into: "the kernel", name: "small file syscall", cost: "~1µs+ per call"}
+)
+
+// unixHelperPrefixes name golang.org/x/sys/unix functions that only convert
+// or pack values in user space and never enter the kernel.
+var unixHelperPrefixes = []string{
+	"Byte", "Cmsg", "Errno", "Major", "Minor", "Mkdev", "Nsec", "Parse", "Signal",
+	"String", "Timespec", "Timeval", "UnixRights",
+}
+
+var syscallLoopAnalyzer = &analysis.Analyzer{
+	Name:     "perf_batch_syscalls_cgo",
+	Doc:      "reports cgo calls, raw syscalls, and small unbuffered file I/O inside loops",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_batch_syscalls_cgo")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_batch_syscalls_cgo not found")
+		}
+
+		if syscallLoopMaxBuffer <= 0 {
+			return nil, fmt.Errorf("max_buffer must be positive, got %d", syscallLoopMaxBuffer)
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+			call, _ := node.(*ast.CallExpr)
+			if !push || !insideLoopBody(stack) {
+				return true
+			}
+			name, class, ok := boundaryCall(pass, call)
+			if !ok {
+				return true
+			}
+			advice := "batch the work so one call crosses the boundary for many items"
+			if class == fileBoundary {
+				advice = "wrap the file with bufio or read and write larger blocks"
+			}
+			report(pass, call.Pos(), rule, fmt.Sprintf(
+				"%s crosses into %s on every iteration (overhead class: %s, %s); %s",
+				name, class.into, class.name, class.cost, advice))
+			return true
+		})
+
+		return nil, nil
+	},
+}
+
+// boundaryCall reports whether call crosses into C or the kernel and names
+// the callee.
+func boundaryCall(pass *analysis.Pass, call *ast.CallExpr) (string, boundaryClass, bool) {
+	if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+		if ident, ok := sel.X.(*ast.Ident); ok {
+			if pkg, ok := pass.TypesInfo.Uses[ident].(*types.PkgName); ok && pkg.Imported().Path() == "C" {
+				return "C." + sel.Sel.Name, cgoBoundary, true
+			}
+		}
+	}
+
+	fn := typeutil.StaticCallee(pass.TypesInfo, call)
+	if fn == nil || fn.Pkg() == nil {
+		return "", boundaryClass{}, false
+	}
+	// cgo rewrites C.f into calls of _Cfunc_f in the files vet analyzes.
+	if name, ok := strings.CutPrefix(fn.Name(), "_Cfunc_"); ok {
+		return "C." + name, cgoBoundary, true
+	}
+
+	recv := fn.Signature().Recv()
+	switch path := fn.Pkg().Path(); {
+	case recv == nil && path == "syscall":
+		if strings.HasPrefix(fn.Name(), "Syscall") || strings.HasPrefix(fn.Name(), "RawSyscall") {
+			return "syscall." + fn.Name(), syscallBoundary, true
+		}
+	case recv == nil && path == "golang.org/x/sys/unix":
+		if !fn.Exported() || hasAnyPrefix(fn.Name(), unixHelperPrefixes) {
+			return "", boundaryClass{}, false
+		}
+		return "unix." + fn.Name(), syscallBoundary, true
+	case recv != nil && path == "os" && isSmallFileIO(pass, fn, call):
+		return "(*os.File)." + fn.Name(), fileBoundary, true
+	}
+	return "", boundaryClass{}, false
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSmallFileIO reports whether call reads or writes an *os.File directly
+// with a buffer known to hold at most max_buffer bytes.
+func isSmallFileIO(pass *analysis.Pass, fn *types.Func, call *ast.CallExpr) bool {
+	ptr, ok := fn.Signature().Recv().Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || named.Obj().Name() != "File" {
+		return false
+	}
+	switch fn.Name() {
+	case "Read", "Write", "ReadAt", "WriteAt", "WriteString":
+	default:
+		return false
+	}
+	if len(call.Args) == 0 {
+		return false
+	}
+	size, ok := staticByteLen(pass, call.Args[0], 0)
+	return ok && size > 0 && size <= int64(syscallLoopMaxBuffer)
+}
+
+// staticByteLen returns an upper bound on the length of a byte slice or
+// string expression when one is known at compile time, following local
+// variables to the expression that initialized them.
+func staticByteLen(pass *analysis.Pass, expr ast.Expr, depth int) (int64, bool) {
+	if depth > 4 {
+		return 0, false
+	}
+	expr = ast.Unparen(expr)
+	if tv, ok := pass.TypesInfo.Types[expr]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		return int64(len(constant.StringVal(tv.Value))), true
+	}
+	if n, ok := constArrayLen(pass.TypesInfo.TypeOf(expr)); ok {
+		return n, true
+	}
+
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		if typ := pass.TypesInfo.TypeOf(e); typ != nil && isSliceOrMap(typ) {
+			for _, elt := range e.Elts {
+				if _, keyed := elt.(*ast.KeyValueExpr); keyed {
+					return 0, false
+				}
+			}
+			return int64(len(e.Elts)), true
+		}
+	case *ast.CallExpr:
+		if tv, ok := pass.TypesInfo.Types[e.Fun]; ok && tv.IsType() && len(e.Args) == 1 {
+			return staticByteLen(pass, e.Args[0], depth+1)
+		}
+		if ident, ok := ast.Unparen(e.Fun).(*ast.Ident); ok && ident.Name == "make" && len(e.Args) >= 2 {
+			if _, builtin := pass.TypesInfo.Uses[ident].(*types.Builtin); builtin {
+				return constInt(pass, e.Args[1])
+			}
+		}
+	case *ast.SliceExpr:
+		return sliceLen(pass, e, depth)
+	case *ast.Ident:
+		if init := localInit(pass, e); init != nil {
+			return staticByteLen(pass, init, depth+1)
+		}
+	}
+	return 0, false
+}
+
+// sliceLen bounds the length of x[lo:hi] by hi-lo when hi is a constant or lo
+// plus a constant, and by the length of x otherwise.
+func sliceLen(pass *analysis.Pass, e *ast.SliceExpr, depth int) (int64, bool) {
+	var lo int64
+	if e.Low != nil {
+		lo, _ = constInt(pass, e.Low)
+	}
+	if e.High != nil {
+		if bin, ok := ast.Unparen(e.High).(*ast.BinaryExpr); ok && e.Low != nil && bin.Op == token.ADD &&
+			types.ExprString(bin.X) == types.ExprString(e.Low) {
+			return constInt(pass, bin.Y)
+		}
+		if hi, ok := constInt(pass, e.High); ok {
+			return hi - lo, hi >= lo
+		}
+	}
+	n, ok := staticByteLen(pass, e.X, depth+1)
+	return n - lo, ok && n >= lo
+}
+
+func constInt(pass *analysis.Pass, expr ast.Expr) (int64, bool) {
+	tv, ok := pass.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil {
+		return 0, false
+	}
+	return constant.Int64Val(constant.ToInt(tv.Value))
+}
+
+func constArrayLen(typ types.Type) (int64, bool) {
+	if typ == nil {
+		return 0, false
+	}
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	array, ok := typ.Underlying().(*types.Array)
+	if !ok {
+		return 0, false
+	}
+	return array.Len(), true
+}
+
+// localInit returns the expression that initialized the local variable ident
+// refers to, as in buf := make([]byte, 1) or var buf = ..., or nil.
+func localInit(pass *analysis.Pass, ident *ast.Ident) ast.Expr {
+	obj, ok := pass.TypesInfo.Uses[ident].(*types.Var)
+	if !ok || obj.Pkg() != pass.Pkg || obj.Parent() == obj.Pkg().Scope() {
+		return nil
+	}
+	file := fileFor(pass, obj.Pos())
+	if file == nil {
+		return nil
+	}
+	var init ast.Expr
+	ast.Inspect(file, func(n ast.Node) bool {
+		if init != nil || n == nil || n.Pos() > obj.Pos() || n.End() <= obj.Pos() {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE && len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					if id, ok := lhs.(*ast.Ident); ok && pass.TypesInfo.Defs[id] == obj {
+						init = n.Rhs[i]
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == len(n.Values) {
+				for i, name := range n.Names {
+					if pass.TypesInfo.Defs[name] == obj {
+						init = n.Values[i]
+					}
+				}
+			}
+		}
+		return true
+	})
+	return init
+}
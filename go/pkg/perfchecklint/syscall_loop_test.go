@@ -0,0 +1,131 @@
+package perfchecklint
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyscallLoopFlagsSmallFileIO(t *testing.T) {
+	src := `package sample
+
+import (
+	"bufio"
+	"os"
+)
+
+func copyBytes(in, out *os.File, n int) {
+	one := make([]byte, 1)
+	var header [8]byte
+	big := make([]byte, 64<<10)
+	w := bufio.NewWriter(out)
+	for i := range n {
+		in.Read(one)
+		out.Write(header[:])
+		out.WriteString("\n")
+		out.Write(big[i : i+2])
+		in.Read(big)
+		w.Write(one)
+	}
+	out.Write(one)
+}
+`
+
+	diags := runAnalyzerOnSource(t, syscallLoopAnalyzer, "file_io.go", src)
+	require.Len(t, diags, 4)
+	require.Contains(t, diags[0].Message, "[perf_batch_syscalls_cgo] (*os.File).Read crosses into the kernel "+
+		"on every iteration (overhead class: small file syscall, ~1µs+ per call); "+
+		"wrap the file with bufio or read and write larger blocks")
+	require.Contains(t, diags[1].Message, "(*os.File).Write crosses")
+	require.Contains(t, diags[2].Message, "(*os.File).WriteString crosses")
+	require.Contains(t, diags[3].Message, "(*os.File).Write crosses")
+
+	setAnalyzerFlag(t, syscallLoopAnalyzer, "max_buffer", "4")
+	require.Len(t, runAnalyzerOnSource(t, syscallLoopAnalyzer, "file_io.go", src), 3)
+}
+
+func TestSyscallLoopFlagsRawSyscalls(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("syscall.SYS_GETPID is linux-specific")
+	}
+	src := `package sample
+
+import "syscall"
+
+func pids(n int) {
+	for range n {
+		syscall.Syscall(syscall.SYS_GETPID, 0, 0, 0)
+		_ = syscall.Getpid()
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, syscallLoopAnalyzer, "raw.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "syscall.Syscall crosses into the kernel on every iteration "+
+		"(overhead class: syscall, ~0.5-1µs per call); batch the work so one call crosses the boundary for many items")
+}
+
+const unixStubSource = `package unix
+
+func Pwrite(fd int, p []byte, offset int64) (int, error) { return 0, nil }
+
+func ByteSliceFromString(s string) ([]byte, error) { return nil, nil }
+`
+
+const cgoLoopSource = `package sample
+
+// #include <zlib.h>
+import "C"
+
+import "golang.org/x/sys/unix"
+
+func store(fd int, items [][]byte) {
+	for i, item := range items {
+		C.compress(nil, nil, nil, 0)
+		unix.Pwrite(fd, item, int64(i))
+		unix.ByteSliceFromString("x")
+	}
+	C.compress(nil, nil, nil, 0)
+}
+`
+
+func TestSyscallLoopFlagsCgoAndUnixCalls(t *testing.T) {
+	fset := token.NewFileSet()
+	check := func(path, src string, conf *types.Config) ([]*ast.File, *types.Info, *types.Package) {
+		file, err := parser.ParseFile(fset, path+".go", src, parser.ParseComments)
+		require.NoError(t, err)
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		pkg, err := conf.Check(path, fset, []*ast.File{file}, info)
+		require.NoError(t, err)
+		return []*ast.File{file}, info, pkg
+	}
+
+	_, _, unix := check("golang.org/x/sys/unix", unixStubSource, &types.Config{})
+	files, info, pkg := check("sample", cgoLoopSource, &types.Config{
+		FakeImportC: true,
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == unix.Path() {
+				return unix, nil
+			}
+			return importer.Default().Import(path)
+		}),
+	})
+
+	diags, _ := runAnalyzerGraph(t, syscallLoopAnalyzer, fset, files, info, pkg, nil)
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0].Message, "C.compress crosses into C on every iteration (overhead class: cgo")
+	require.Contains(t, diags[1].Message, "unix.Pwrite crosses into the kernel")
+}
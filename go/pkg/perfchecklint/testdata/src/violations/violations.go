@@ -2,12 +2,17 @@ package violations
 
 import (
 	"container/list"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
 	"reflect"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 )
 
 // perf_avoid_string_concat_loop
@@ -39,6 +44,14 @@ func collect(numbers []int) []int {
 	return out
 }
 
+func lengths(words []string) map[string]int {
+	out := make(map[string]int)
+	for _, w := range words {
+		out[w] = len(w) // want "[perf_preallocate_collections]"
+	}
+	return out
+}
+
 // perf_avoid_reflection_dynamic
 func kinds(values []any) []reflect.Kind {
 	var result []reflect.Kind
@@ -128,6 +141,145 @@ func newPoint(x, y int) *point {
 	return &point{x: x, y: y} // want "[perf_prefer_stack_alloc]"
 }
 
+// perf_inline_byte_to_string_mapkey
+func lookupBytes(m map[string]int, b []byte) int {
+	key := string(b) // want "[perf_inline_byte_to_string_mapkey]"
+	return m[key]
+}
+
+// perf_hoist_loop_invariant_call
+func prefixAll(items []string, prefix string) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, strings.ToLower(prefix)+item) // want "[perf_hoist_loop_invariant_call]"
+	}
+	return out
+}
+
+// perf_batch_syscalls_cgo
+func readBytes(f *os.File, n int) error {
+	buf := make([]byte, 1)
+	for range n {
+		if _, err := f.Read(buf); err != nil { // want "[perf_batch_syscalls_cgo]"
+			return err
+		}
+	}
+	return nil
+}
+
+// perf_prefer_strconv
+func label(id int) string {
+	return "item-" + fmt.Sprint(id) // want "[perf_prefer_strconv]"
+}
+
+// perf_builder_grow
+func joinLines(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line) // want "[perf_builder_grow]"
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// perf_reuse_timers
+func consume(events <-chan string, idle time.Duration) {
+	for {
+		select {
+		case <-events:
+		case <-time.After(idle): // want "[perf_reuse_timers]"
+			return
+		}
+	}
+}
+
+// perf_no_blocking_under_lock
+type outbox struct {
+	mu      sync.Mutex
+	pending []string
+	out     chan<- string
+}
+
+func (o *outbox) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, msg := range o.pending {
+		o.out <- msg // want "[perf_no_blocking_under_lock]"
+	}
+	o.pending = o.pending[:0]
+}
+
+// perf_range_value_copy
+type sample struct {
+	id      int
+	history [32]int64
+}
+
+func newest(samples []sample) int64 {
+	var latest int64
+	for _, s := range samples { // want "[perf_range_value_copy]"
+		latest = max(latest, s.history[0])
+	}
+	return latest
+}
+
+// perf_large_param_copy
+func (s sample) oldest() int64 { // want "[perf_large_param_copy]"
+	return s.history[len(s.history)-1]
+}
+
+// perf_avoid_interface_boxing
+func fields(ids []int) []any {
+	var out []any
+	for _, id := range ids {
+		out = append(out, id) // want "[perf_avoid_interface_boxing]"
+	}
+	return out
+}
+
+// perf_avoid_codec_reflection_loop
+func encodeAll(w io.Writer, items []sample) error {
+	for _, item := range items {
+		data, err := json.Marshal(item.id) // want "[perf_avoid_codec_reflection_loop]"
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// perf_reuse_http_client
+func forward(w http.ResponseWriter, r *http.Request) {
+	client := &http.Client{Transport: &http.Transport{MaxIdleConns: 10}} // want "[perf_reuse_http_client]"
+	resp, err := client.Do(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	_ = resp.Body.Close()
+}
+
+// perf_avoid_n_plus_one_query
+func names(db *sql.DB, ids []int) ([]string, error) {
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		var name string
+		if err := db.QueryRow("SELECT name FROM users WHERE id = ?", id).Scan(&name); err != nil { // want "[perf_avoid_n_plus_one_query]"
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// perf_ignore_directive
+//
+//perfcheck:ignore perf_no_defer_in_loop
+func deferFree() {} // want "[perf_ignore_directive]"
+
 // helper to keep package referenced
 func use(values ...any) {
 	fmt.Fprint(io.Discard, values...)
@@ -0,0 +1,208 @@
+package perfchecklint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"go/version"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/m-v-kalashnikov/perfcheck/go/internal/ruleset"
+)
+
+var timerReuseAnalyzer = &analysis.Analyzer{
+	Name:     "perf_reuse_timers",
+	Doc:      "reports time.After inside loops and time.Tick tickers that are never stopped",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
+	Run: func(pass *analysis.Pass) (any, error) {
+		rule, ok := ruleset.MustDefault().RuleByID("perf_reuse_timers")
+		if !ok {
+			return nil, fmt.Errorf("rule perf_reuse_timers not found")
+		}
+
+		ins, _ := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+		if ins == nil {
+			return nil, fmt.Errorf("missing inspector dependency")
+		}
+
+		ins.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node, push bool, stack []ast.Node) bool {
+			call, _ := node.(*ast.CallExpr)
+			if !push || len(call.Args) != 1 {
+				return true
+			}
+			fn := typeutil.StaticCallee(pass.TypesInfo, call)
+			if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "time" || fn.Signature().Recv() != nil {
+				return true
+			}
+			loop := innermostLoop(stack)
+			switch {
+			case fn.Name() == "After" && loop != nil:
+				detail := "time.After allocates a new timer on every iteration; create one time.NewTimer " +
+					"before the loop and Reset it each iteration"
+				if fix, ok := timerFix(pass, loop, call); ok {
+					reportWithFixes(pass, call, rule, detail, fix)
+					return true
+				}
+				report(pass, call.Pos(), rule, detail)
+			case fn.Name() == "Tick" && loop != nil:
+				report(pass, call.Pos(), rule, "time.Tick starts a ticker on every iteration that can never be "+
+					"stopped; create one time.NewTicker before the loop and Stop it when done")
+			case fn.Name() == "Tick" && !runsForProgramLifetime(pass, stack):
+				detail := "time.Tick starts a ticker that can never be stopped; use time.NewTicker and defer its Stop"
+				if fix, ok := tickerFix(pass, call); ok {
+					reportWithFixes(pass, call, rule, detail, fix)
+					return true
+				}
+				report(pass, call.Pos(), rule, detail)
+			}
+			return true
+		})
+
+		return nil, nil
+	},
+}
+
+// runsForProgramLifetime reports whether the innermost node of stack belongs
+// to a package-level initializer or to main.main itself, where a ticker that
+// is never stopped lives exactly as long as it is needed.
+func runsForProgramLifetime(pass *analysis.Pass, stack []ast.Node) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch fn := stack[i].(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.FuncDecl:
+			return pass.Pkg.Name() == "main" && fn.Recv == nil && fn.Name.Name == "main"
+		}
+	}
+	return true
+}
+
+// timerFix creates a timer before loop, resets it right before the statement
+// that called time.After, and receives from the timer's channel instead. Reset
+// only discards a stale expiry on Go 1.23 and later, so older modules get no
+// fix.
+func timerFix(pass *analysis.Pass, loop ast.Stmt, call *ast.CallExpr) (analysis.SuggestedFix, bool) {
+	if goVersion := pass.Pkg.GoVersion(); goVersion != "" && version.Compare(goVersion, "go1.23") < 0 {
+		return analysis.SuggestedFix{}, false
+	}
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return analysis.SuggestedFix{}, false
+	}
+	loopPath := enclosingPath(pass, loop)
+	fnBody := enclosingFunc(loopPath)
+	if fnBody == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	duration := call.Args[0]
+	if !stableAcrossLoop(pass, &loopScope{pass: pass, loop: loop, fn: fnBody}, duration) {
+		return analysis.SuggestedFix{}, false
+	}
+
+	stmt := enclosingListStmt(enclosingPath(pass, call), loop)
+	if stmt == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	anchor := statementAnchor(loopPath, loop)
+	timer := freshName(pass, anchor.Pos(), fnBody, "timer")
+	d := types.ExprString(duration)
+	return analysis.SuggestedFix{
+		Message: fmt.Sprintf("Reuse one time.NewTimer(%s) across iterations", d),
+		TextEdits: []analysis.TextEdit{
+			{
+				Pos: anchor.Pos(),
+				End: anchor.Pos(),
+				NewText: []byte(fmt.Sprintf("%s := %s.NewTimer(%s)\n%s",
+					timer, types.ExprString(sel.X), d, lineIndent(pass, anchor.Pos()))),
+			},
+			{
+				Pos:     stmt.Pos(),
+				End:     stmt.Pos(),
+				NewText: []byte(fmt.Sprintf("%s.Reset(%s)\n%s", timer, d, lineIndent(pass, stmt.Pos()))),
+			},
+			{Pos: call.Pos(), End: call.End(), NewText: []byte(timer + ".C")},
+		},
+	}, true
+}
+
+// tickerFix starts a ticker right before the statement that called time.Tick,
+// stops it when the function returns, and receives from its channel instead.
+func tickerFix(pass *analysis.Pass, call *ast.CallExpr) (analysis.SuggestedFix, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return analysis.SuggestedFix{}, false
+	}
+	if tv := pass.TypesInfo.Types[call.Args[0]]; tv.Value == nil && !isPlainRef(call.Args[0]) {
+		return analysis.SuggestedFix{}, false
+	}
+	path := enclosingPath(pass, call)
+	fnBody := enclosingFunc(path)
+	stmt := enclosingListStmt(path, nil)
+	if fnBody == nil || stmt == nil {
+		return analysis.SuggestedFix{}, false
+	}
+	ticker := freshName(pass, stmt.Pos(), fnBody, "ticker")
+	indent := lineIndent(pass, stmt.Pos())
+	d := types.ExprString(call.Args[0])
+	return analysis.SuggestedFix{
+		Message: fmt.Sprintf("Use time.NewTicker(%s) and stop it on return", d),
+		TextEdits: []analysis.TextEdit{
+			{
+				Pos: stmt.Pos(),
+				End: stmt.Pos(),
+				NewText: []byte(fmt.Sprintf("%s := %s.NewTicker(%s)\n%sdefer %s.Stop()\n%s",
+					ticker, types.ExprString(sel.X), d, indent, ticker, indent)),
+			},
+			{Pos: call.Pos(), End: call.End(), NewText: []byte(ticker + ".C")},
+		},
+	}, true
+}
+
+// stableAcrossLoop reports whether expr is a constant or a local variable
+// that keeps its value for the whole of scope's loop.
+func stableAcrossLoop(pass *analysis.Pass, scope *loopScope, expr ast.Expr) bool {
+	if tv, ok := pass.TypesInfo.Types[expr]; ok && tv.Value != nil {
+		return true
+	}
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	v, ok := pass.TypesInfo.Uses[ident].(*types.Var)
+	return ok && scope.stableVar(v)
+}
+
+// enclosingListStmt returns the innermost statement on path that sits in a
+// statement list, so new statements can be inserted in front of it. It stops
+// at the enclosing function and, when within is not nil, at within. The
+// communication of a select case is not in a list and is skipped.
+func enclosingListStmt(path []ast.Node, within ast.Node) ast.Stmt {
+	for i := 0; i+1 < len(path); i++ {
+		switch path[i].(type) {
+		case *ast.FuncLit, *ast.FuncDecl:
+			return nil
+		case *ast.CaseClause, *ast.CommClause:
+			continue
+		}
+		if path[i] == within {
+			return nil
+		}
+		stmt, ok := path[i].(ast.Stmt)
+		if !ok {
+			continue
+		}
+		switch parent := path[i+1].(type) {
+		case *ast.BlockStmt, *ast.CaseClause:
+			return stmt
+		case *ast.CommClause:
+			if parent.Comm != stmt {
+				return stmt
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package perfchecklint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimerReuseReusesTimerAcrossSelectLoop(t *testing.T) {
+	src := `package sample
+
+import "time"
+
+func consume(events <-chan int, idle time.Duration) int {
+	total := 0
+	for {
+		select {
+		case ev := <-events:
+			total += ev
+		case <-time.After(idle):
+			return total
+		}
+	}
+}
+`
+	want := `package sample
+
+import "time"
+
+func consume(events <-chan int, idle time.Duration) int {
+	total := 0
+	timer := time.NewTimer(idle)
+	for {
+		timer.Reset(idle)
+		select {
+		case ev := <-events:
+			total += ev
+		case <-timer.C:
+			return total
+		}
+	}
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, timerReuseAnalyzer, "consume.go", src)
+	require.Len(t, diags, 1)
+	require.Contains(t, diags[0].Message, "[perf_reuse_timers] time.After allocates a new timer on every "+
+		"iteration; create one time.NewTimer before the loop and Reset it each iteration")
+	require.Equal(t, want, applySuggestedFix(t, fset, src, diags[0]))
+}
+
+func TestTimerReuseReportsVaryingDurationWithoutFix(t *testing.T) {
+	src := `package sample
+
+import "time"
+
+func retry(attempts int, try func() bool) {
+	delay := time.Millisecond
+	for range attempts {
+		if try() {
+			return
+		}
+		<-time.After(delay)
+		delay *= 2
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, timerReuseAnalyzer, "retry.go", src)
+	require.Len(t, diags, 1)
+	require.Empty(t, diags[0].SuggestedFixes)
+}
+
+func TestTimerReuseStopsTickers(t *testing.T) {
+	src := `package sample
+
+import "time"
+
+func poll(done <-chan struct{}, check func()) {
+	for range 3 {
+		_ = time.Tick(time.Second)
+	}
+	tick := time.Tick(time.Second)
+	for {
+		select {
+		case <-done:
+			return
+		case <-tick:
+			check()
+		}
+	}
+}
+`
+
+	fset, diags := runAnalyzerOnSourceWithFileSet(t, timerReuseAnalyzer, "poll.go", src)
+	require.Len(t, diags, 2)
+	require.Contains(t, diags[0].Message, "time.Tick starts a ticker on every iteration that can never be stopped")
+	require.Empty(t, diags[0].SuggestedFixes)
+	require.Contains(t, diags[1].Message, "time.Tick starts a ticker that can never be stopped; "+
+		"use time.NewTicker and defer its Stop")
+	require.Contains(t, applySuggestedFix(t, fset, src, diags[1]),
+		"\tticker := time.NewTicker(time.Second)\n\tdefer ticker.Stop()\n\ttick := ticker.C\n")
+}
+
+func TestTimerReuseIgnoresSingleShotsAndProgramLifetimeTickers(t *testing.T) {
+	src := `package main
+
+import "time"
+
+var heartbeat = time.Tick(time.Minute)
+
+func wait(d time.Duration) {
+	<-time.After(d)
+	for range 3 {
+		go func() { <-time.After(d) }()
+	}
+}
+
+func main() {
+	for range time.Tick(time.Second) {
+		wait(time.Millisecond)
+	}
+}
+`
+
+	diags := runAnalyzerOnSource(t, timerReuseAnalyzer, "main.go", src)
+	require.Empty(t, diags)
+}
@@ -15,7 +15,7 @@ import (
 var writerPreferBytesAnalyzer = &analysis.Analyzer{
 	Name:     "perf_writer_prefer_bytes",
 	Doc:      "reports string conversions when writing byte slices",
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, directivesAnalyzer, configAnalyzer, profileAnalyzer},
 	Run: func(pass *analysis.Pass) (any, error) {
 		rule, ok := ruleset.MustDefault().RuleByID("perf_writer_prefer_bytes")
 		if !ok {
@@ -17,9 +17,19 @@ pub struct Rule {
     pub severity: String,
     pub problem_summary: String,
     pub fix_hint: String,
+    /// Optional `key=value` tuning defaults from the `params` column.
+    pub params: Vec<(String, String)>,
     pub code: u32,
 }
 
+impl Rule {
+    /// Returns the registry default for the named tuning parameter.
+    #[must_use]
+    pub fn param(&self, name: &str) -> Option<&str> {
+        self.params.iter().find(|(key, _)| key == name).map(|(_, value)| value.as_str())
+    }
+}
+
 /// Immutable rule registry with fast lookups by id or language.
 pub struct RuleRegistry {
     all: Vec<Rule>,
@@ -52,7 +62,7 @@ impl RuleRegistry {
             }
 
             let parts: Vec<&str> = line.split('\t').collect();
-            if parts.len() != 7 {
+            if parts.len() != 7 && parts.len() != 8 {
                 return Err(format!("invalid field count on line {}", line_idx + 1));
             }
 
@@ -74,6 +84,12 @@ impl RuleRegistry {
                 return Err(format!("missing guidance fields on line {}", line_idx + 1));
             }
 
+            let params = match parts.get(7) {
+                Some(raw) => parse_params(raw)
+                    .ok_or_else(|| format!("malformed params on line {}", line_idx + 1))?,
+                None => Vec::new(),
+            };
+
             let mut rule = Rule {
                 id: parts[0].trim().to_string(),
                 langs,
@@ -82,6 +98,7 @@ impl RuleRegistry {
                 severity: parts[4].trim().to_ascii_lowercase(),
                 problem_summary: problem_summary.to_string(),
                 fix_hint: fix_hint.to_string(),
+                params,
                 code: 0,
             };
 
@@ -160,6 +177,19 @@ impl<'a> Iterator for RuleIter<'a> {
     }
 }
 
+fn parse_params(raw: &str) -> Option<Vec<(String, String)>> {
+    let mut params = Vec::new();
+    for part in raw.split(',').map(str::trim).filter(|part| !part.is_empty()) {
+        let (key, value) = part.split_once('=')?;
+        let (key, value) = (key.trim(), value.trim());
+        if key.is_empty() || value.is_empty() {
+            return None;
+        }
+        params.push((key.to_string(), value.to_string()));
+    }
+    Some(params)
+}
+
 fn hash(id: &str) -> u32 {
     const OFFSET: u32 = 0x811C_9DC5;
     const PRIME: u32 = 0x0100_0193;
@@ -195,6 +225,21 @@ mod tests {
         assert_eq!(first_run, second_run);
     }
 
+    #[test]
+    fn parses_optional_params() {
+        let data =
+            "id\tlangs\tdescription\tcategory\tseverity\tproblem_summary\tfix_hint\tparams\n"
+                .to_string() +
+                "tuned\tgo\tdesc\tcat\twarning\twhy\tfix\tmax_size=64\n" +
+                "plain\tgo\tdesc\tcat\twarning\twhy\tfix\n";
+        let registry = RuleRegistry::from_tsv(&data).expect("parse");
+        assert_eq!(registry.rule("tuned").and_then(|rule| rule.param("max_size")), Some("64"));
+        assert_eq!(registry.rule("plain").and_then(|rule| rule.param("max_size")), None);
+
+        let bad = "header\nrule\tgo\tdesc\tcat\twarning\twhy\tfix\tmax_size\n";
+        assert!(RuleRegistry::from_tsv(bad).is_err());
+    }
+
     #[test]
     fn requires_guidance_fields() {
         let data = "id\tlangs\tdescription\tcategory\tseverity\tproblem_summary\tfix_hint\n"